@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 )
 
 type MutateRequest struct {
@@ -19,14 +20,32 @@ type MutationItem struct {
 	CreateOrReplace   *json.RawMessage `json:"createOrReplace,omitempty"`
 	Delete            *Delete          `json:"delete,omitempty"`
 	Patch             *Patch           `json:"patch,omitempty"`
+
+	// Raw is a pre-serialized mutation. If set, it is sent verbatim and all other fields
+	// are ignored.
+	Raw json.RawMessage `json:"-"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (i *MutationItem) MarshalJSON() ([]byte, error) {
+	if i.Raw != nil {
+		return i.Raw, nil
+	}
+
+	type mutationItem MutationItem
+	return json.Marshal((*mutationItem)(i))
 }
 
 type Delete struct {
-	ID string `json:"id"`
+	ID string `json:"id,omitempty"`
+
+	// Query is a GROQ filter selecting the documents to delete, used instead of ID.
+	Query  string                      `json:"query,omitempty"`
+	Params map[string]*json.RawMessage `json:"params,omitempty"`
 }
 
 type Patch struct {
-	ID             string                      `json:"id"`
+	ID             string                      `json:"id,omitempty"`
 	IfRevisionID   string                      `json:"ifRevisionID,omitempty"`
 	Query          string                      `json:"query,omitempty"`
 	Set            map[string]*json.RawMessage `json:"set,omitempty"`
@@ -46,6 +65,11 @@ type Insert struct {
 }
 
 type MutateResultItem struct {
+	// ID and Operation, such as "create", "update" or "delete", are set if the mutation was
+	// performed with returnIds.
+	ID        string `json:"id,omitempty"`
+	Operation string `json:"operation,omitempty"`
+
 	Document *json.RawMessage `json:"document"`
 }
 
@@ -77,6 +101,9 @@ type QueryResponse struct {
 
 	// Result is the raw JSON of the query result.
 	Result *json.RawMessage `json:"result"`
+
+	// Explain is the raw JSON of the query execution plan, if requested.
+	Explain *json.RawMessage `json:"explain,omitempty"`
 }
 
 // GetDocumentsResponse holds result of GET documents API call.
@@ -85,5 +112,23 @@ type GetDocumentsResponse struct {
 	Documents []Document `json:"documents"`
 }
 
+// Unmarshal decodes the documents into dest, which must be a pointer to a slice, such as
+// *[]Post. If there are no documents, the slice is set to an empty slice.
+func (r *GetDocumentsResponse) Unmarshal(dest interface{}) error {
+	docs := r.Documents
+	if docs == nil {
+		docs = []Document{}
+	}
+
+	b, err := json.Marshal(docs)
+	if err != nil {
+		return fmt.Errorf("marshaling documents: %w", err)
+	}
+	if err := json.Unmarshal(b, dest); err != nil {
+		return fmt.Errorf("unmarshaling documents: %w", err)
+	}
+	return nil
+}
+
 // Document is a map of document attributes
 type Document map[string]interface{}
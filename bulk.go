@@ -0,0 +1,70 @@
+package sanity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// BulkOption is an option for BulkCreateOrReplace.
+type BulkOption func(o *bulkOptions)
+
+type bulkOptions struct {
+	continueOnError bool
+}
+
+// BulkContinueOnError returns an option that makes BulkCreateOrReplace carry on with the
+// remaining batches when a batch fails, instead of stopping.
+func BulkContinueOnError(enable bool) BulkOption {
+	return func(o *bulkOptions) { o.continueOnError = enable }
+}
+
+// BulkCreateOrReplace creates or replaces the documents in transactions of at most batchSize
+// documents each, which keeps each request within the size limits of the API. This is the
+// usual way to import a large number of documents. Each transaction is applied atomically,
+// but the import as a whole is not: if a batch fails, the batches before it remain applied.
+//
+// The results are returned in order, one per batch sent, so that results[i] is the result
+// of the documents from i*batchSize. By default, the first failed batch stops the import,
+// and the results of the batches before it are returned with its error. With
+// BulkContinueOnError, the remaining batches are still sent, the result of each failed
+// batch is nil, and the errors of all failed batches are returned joined. On API request
+// failure, the errors wrap errors of type *RequestError.
+func (c *Client) BulkCreateOrReplace(
+	ctx context.Context, docs []interface{}, batchSize int, opts ...BulkOption,
+) ([]*MutateResult, error) {
+	if batchSize <= 0 {
+		return nil, fmt.Errorf("batch size must be positive")
+	}
+
+	var o bulkOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var results []*MutateResult
+	var errs []error
+	for start := 0; start < len(docs); start += batchSize {
+		end := start + batchSize
+		if end > len(docs) {
+			end = len(docs)
+		}
+
+		mb := c.Mutate()
+		for _, doc := range docs[start:end] {
+			mb.CreateOrReplace(doc)
+		}
+
+		result, err := mb.Do(ctx)
+		if err != nil {
+			err = fmt.Errorf("documents %d to %d: %w", start, end-1, err)
+			if !o.continueOnError {
+				return results, err
+			}
+			errs = append(errs, err)
+		}
+		results = append(results, result)
+	}
+
+	return results, errors.Join(errs...)
+}
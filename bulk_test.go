@@ -0,0 +1,119 @@
+package sanity_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sanity "github.com/sanity-io/client-go"
+	"github.com/sanity-io/client-go/api"
+)
+
+func TestBulkCreateOrReplace(t *testing.T) {
+	makeDocs := func(n int) []interface{} {
+		docs := make([]interface{}, n)
+		for i := range docs {
+			docs[i] = map[string]string{"_id": fmt.Sprintf("doc%d", i), "_type": "post"}
+		}
+		return docs
+	}
+
+	// serve records the number of mutations in each request, and fails the requests whose
+	// index is in fail.
+	serve := func(s *Suite, fail ...int) *[]int {
+		var sizes []int
+		s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+			var req api.MutateRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			for _, item := range req.Mutations {
+				assert.NotNil(t, item.CreateOrReplace)
+			}
+
+			sizes = append(sizes, len(req.Mutations))
+			for _, i := range fail {
+				if i == len(sizes)-1 {
+					w.WriteHeader(http.StatusBadRequest)
+					_, _ = w.Write([]byte(`{"error":"invalid"}`))
+					return
+				}
+			}
+
+			_, err := w.Write(mustJSONBytes(&api.MutateResponse{TransactionID: fmt.Sprintf("tx%d", len(sizes))}))
+			assert.NoError(t, err)
+		})
+		return &sizes
+	}
+
+	for _, tc := range []struct {
+		desc      string
+		docs      int
+		batchSize int
+		wantSizes []int
+	}{
+		{desc: "partial last batch", docs: 5, batchSize: 2, wantSizes: []int{2, 2, 1}},
+		{desc: "exact batches", docs: 4, batchSize: 2, wantSizes: []int{2, 2}},
+		{desc: "single batch", docs: 3, batchSize: 10, wantSizes: []int{3}},
+		{desc: "no documents", docs: 0, batchSize: 10},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			withSuite(t, func(s *Suite) {
+				sizes := serve(s)
+
+				results, err := s.client.BulkCreateOrReplace(context.Background(), makeDocs(tc.docs), tc.batchSize)
+				require.NoError(t, err)
+				assert.Equal(t, tc.wantSizes, *sizes)
+				assert.Len(t, results, len(tc.wantSizes))
+			})
+		})
+	}
+
+	t.Run("stops on first error", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			sizes := serve(s, 1)
+
+			results, err := s.client.BulkCreateOrReplace(context.Background(), makeDocs(5), 2)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "documents 2 to 3")
+
+			var reqErr *sanity.RequestError
+			assert.True(t, errors.As(err, &reqErr))
+
+			assert.Equal(t, []int{2, 2}, *sizes)
+			require.Len(t, results, 1)
+			assert.Equal(t, "tx1", results[0].TransactionID)
+		})
+	})
+
+	t.Run("continues on error", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			sizes := serve(s, 1, 3)
+
+			results, err := s.client.BulkCreateOrReplace(context.Background(), makeDocs(7), 2,
+				sanity.BulkContinueOnError(true))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "documents 2 to 3")
+			assert.Contains(t, err.Error(), "documents 6 to 6")
+
+			assert.Equal(t, []int{2, 2, 2, 1}, *sizes)
+			require.Len(t, results, 4)
+			assert.Equal(t, "tx1", results[0].TransactionID)
+			assert.Nil(t, results[1])
+			assert.Equal(t, "tx3", results[2].TransactionID)
+			assert.Nil(t, results[3])
+		})
+	})
+
+	t.Run("rejects invalid batch size", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			_, err := s.client.BulkCreateOrReplace(context.Background(), makeDocs(1), 0)
+			require.Error(t, err)
+		})
+	})
+}
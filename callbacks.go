@@ -1,6 +1,25 @@
 package sanity
 
+import (
+	"net/http"
+	"time"
+)
+
 type Callbacks struct {
+	// OnErrorWillRetry is called with the error of a failed attempt before it is retried.
+	// For a retriable HTTP status, the error is of type *RequestError.
 	OnErrorWillRetry func(error)
-	OnQueryResult    func(*QueryResult)
+
+	// OnRetry is called before waiting to retry a failed request. The attempt is the number
+	// of the upcoming retry, starting at 1, and delay is the time until it is made. The
+	// response is that of the failed attempt, with its body already consumed; it is nil if
+	// the attempt failed with a network error.
+	OnRetry func(attempt int, delay time.Duration, resp *http.Response)
+
+	OnQueryResult func(*QueryResult)
+
+	// OnInvalidateTags is called after a successful mutation that has cache tags set with
+	// MutationBuilder.InvalidateTags. It is the place to purge any caches in front of the
+	// API that are keyed by those tags.
+	OnInvalidateTags func(tags []string)
 }
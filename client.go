@@ -1,15 +1,19 @@
 package sanity
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"regexp"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/jpillora/backoff"
@@ -70,6 +74,7 @@ type Client struct {
 	baseAPIURL    url.URL
 	baseQueryURL  url.URL // if useCDN=false, baseQueryURL will be same as baseAPIURL.
 	customHeaders http.Header
+	fixedHeaders  http.Header
 	token         string
 	projectID     string
 	dataset       string
@@ -77,13 +82,83 @@ type Client struct {
 	callbacks     Callbacks
 	setHeaders    func(r *requests.Request)
 	tag           string
+	retryMutation bool
+	userAgent     string
+	retryMax      int
+	timeout       time.Duration
+	proxyURL      string
+	insecureTLS   bool
+	hcBuilt       bool // hc was built for proxyURL and insecureTLS
+	wireTap       func(reqBytes, respBytes []byte)
+	projHeaders   bool
+	basePath      string
+	useNumber     bool
+	corrHeader    string
+	httpTrace     func(*RequestTimings)
+	apiProto      string
+	cdnProto      string
+	gzipMinSize   int
+	logger        *slog.Logger
+	perspective   string
+	loaderWindow  time.Duration
+	loader        *documentLoader
+	maxQueryLen   int
+	exceedPolicy  ExceedPolicy
+	idGenerator   func() string
+	transformers  []func(*json.RawMessage) (*json.RawMessage, error)
 }
 
 type Option func(c *Client)
 
 // WithHTTPClient returns an option for setting a custom HTTP client.
 func WithHTTPClient(client *http.Client) Option {
-	return func(c *Client) { c.hc = client }
+	return func(c *Client) { c.hc, c.hcBuilt = client, false }
+}
+
+// WithProxy returns an option that sends all requests through the HTTP proxy at the given
+// URL, such as "http://proxy.example.com:3128". It cannot be combined with WithHTTPClient;
+// to use a proxy with a custom HTTP client, configure the proxy on its transport instead.
+func WithProxy(proxyURL string) Option {
+	return func(c *Client) { c.proxyURL = proxyURL }
+}
+
+// WithInsecureSkipVerify returns an option that disables verification of the server's TLS
+// certificate, such as for a local emulator or a debugging proxy with a self-signed
+// certificate.
+//
+// WARNING: This makes the client vulnerable to man-in-the-middle attacks, exposing the
+// token and all data it sends and receives. It is meant for local development only and must
+// never be used in production.
+//
+// Like WithProxy, it cannot be combined with WithHTTPClient, but it can be combined with
+// WithProxy.
+func WithInsecureSkipVerify() Option {
+	return func(c *Client) { c.insecureTLS = true }
+}
+
+// DefaultGzipThreshold is the minimum size, in bytes, of request bodies that are compressed
+// when WithRequestGzip is used.
+const DefaultGzipThreshold = 8 * 1024
+
+// WithRequestGzip returns an option that compresses request bodies of at least
+// DefaultGzipThreshold bytes with gzip, such as large mutation batches and queries sent as
+// POST requests. This reduces bandwidth at the cost of some CPU time.
+func WithRequestGzip() Option {
+	return WithRequestGzipThreshold(DefaultGzipThreshold)
+}
+
+// WithRequestGzipThreshold is like WithRequestGzip, but compresses request bodies of at
+// least minSize bytes.
+func WithRequestGzipThreshold(minSize int) Option {
+	return func(c *Client) { c.gzipMinSize = minSize }
+}
+
+// WithLogger returns an option that logs every request attempt at debug level, with its
+// method, URL, status, duration and attempt number (starting at 1), and every retry at
+// warning level.
+// Nothing is logged by default.
+func WithLogger(l *slog.Logger) Option {
+	return func(c *Client) { c.logger = l }
 }
 
 // WithCallbacks returns an option that enables callbacks for common events
@@ -100,6 +175,59 @@ func WithBackoff(b backoff.Backoff) Option {
 	return func(c *Client) { c.backoff = b }
 }
 
+// WithRetryDelays returns an option that configures the delay between retries without the
+// backoff package: the delay starts at min and grows by factor with each retry, up to max.
+// Like the default, each delay is randomized within that bound, so it never falls below min
+// or exceeds max. A factor of 0 or less uses the default of 2. For more control, use
+// WithBackoff.
+func WithRetryDelays(min, max time.Duration, factor float64) Option {
+	return func(c *Client) {
+		c.backoff = backoff.Backoff{Min: min, Max: max, Factor: factor, Jitter: true}
+	}
+}
+
+// WithRetryMax returns an option that limits the number of times a failed request is
+// retried. Once exhausted, the error from the last attempt is returned. A value of 0
+// disables retries. By default, requests are retried until the context is cancelled.
+func WithRetryMax(n int) Option {
+	return func(c *Client) { c.retryMax = n }
+}
+
+// WithTimeout returns an option that sets a default timeout for each call, covering all
+// retries. It only applies when the context passed to the call has no deadline, so it never
+// shortens a deadline set by the caller.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) { c.timeout = d }
+}
+
+// WithProtocols returns an option that restricts the HTTP protocol used for requests to the
+// API host and to the CDN host, each of which may be ProtocolHTTP1, ProtocolHTTP2 or empty
+// to let the protocol be negotiated. This is useful when, for example, the CDN performs
+// better over HTTP/2 while a proxy in front of the API only supports HTTP/1.1. If the CDN
+// is not in use, cdnProto is ignored. The HTTP client must use an *http.Transport, which
+// is copied rather than modified.
+func WithProtocols(apiProto, cdnProto string) Option {
+	return func(c *Client) {
+		c.apiProto = apiProto
+		c.cdnProto = cdnProto
+	}
+}
+
+// WithWireTap returns an option that calls fn with the body of every request as it is sent,
+// and the body of its response as it is received, for recording interactions as test
+// fixtures. Each attempt of a retried request is captured separately. The response body is
+// captured as it is read, so streaming is not affected, and fn is called once the body is
+// closed; if it was not read to the end, only the part read is captured. Headers, including
+// the API token, are not captured. Request bodies are only buffered if a tap is set.
+func WithWireTap(fn func(reqBytes, respBytes []byte)) Option {
+	return func(c *Client) { c.wireTap = fn }
+}
+
+// WithDataset returns an option that sets the dataset. This is mostly useful with Clone.
+func WithDataset(dataset string) Option {
+	return func(c *Client) { c.dataset = dataset }
+}
+
 // WithToken returns an option that sets the API token to use.
 func WithToken(t string) Option {
 	return func(c *Client) { c.token = t }
@@ -121,6 +249,38 @@ func WithHTTPHost(scheme, host string) Option {
 	}
 }
 
+// WithBaseURLPath returns an option that prepends a path to that of every request, for when
+// the API is served under a prefix by a gateway, such as "/sanity" for
+// https://example.com/sanity/v2021-03-25/data/query/production. It is usually combined with
+// WithHTTPHost.
+func WithBaseURLPath(prefix string) Option {
+	return func(c *Client) { c.basePath = prefix }
+}
+
+// WithJSONNumbers returns an option that decodes numbers in untyped values, such as the
+// fields of an api.Document or a query result unmarshaled into interface{}, as json.Number
+// instead of float64. This preserves the precision of integers larger than 2^53, such as
+// timestamps or IDs stored as numbers. Typed destinations, such as struct fields of type
+// int64, are not affected.
+func WithJSONNumbers(enable bool) Option {
+	return func(c *Client) { c.useNumber = enable }
+}
+
+// WithCorrelationHeader returns an option that sets the header in which the correlation ID
+// stored in the context under CorrelationIDKey is sent. The default is
+// DefaultCorrelationHeader.
+func WithCorrelationHeader(name string) Option {
+	return func(c *Client) { c.corrHeader = name }
+}
+
+// WithHTTPTrace returns an option that calls fn with a breakdown of the time taken by every
+// request attempt, such as DNS lookup, connecting, TLS handshake and time to first byte, to
+// tell network latency from server latency. It is called once the response body is closed,
+// or when the attempt fails without a response.
+func WithHTTPTrace(fn func(*RequestTimings)) Option {
+	return func(c *Client) { c.httpTrace = fn }
+}
+
 // WithHTTPHeader returns an option for setting a custom HTTP header.
 // These headers are set in addition to the ones defined in Client.setHeaders().
 // If a custom header is added with the same key as one of default header, then
@@ -134,6 +294,94 @@ func WithHTTPHeader(key, value string) Option {
 	}
 }
 
+// WithHTTPHeaderReplace returns an option for setting a custom HTTP header that replaces
+// any value of the same key, including the default headers and those added with
+// WithHTTPHeader. This is useful for endpoints that require a single Accept value.
+func WithHTTPHeaderReplace(key, value string) Option {
+	return func(c *Client) {
+		if c.fixedHeaders == nil {
+			c.fixedHeaders = make(http.Header)
+		}
+		c.fixedHeaders.Set(key, value)
+	}
+}
+
+// WithProjectHeaders returns an option that makes every request carry the project ID and
+// dataset of the client in the X-Sanity-Project-ID and X-Sanity-Dataset headers, for proxies
+// in front of the API that route or authorize requests without parsing the URL.
+func WithProjectHeaders(enable bool) Option {
+	return func(c *Client) { c.projHeaders = enable }
+}
+
+// WithMutationRetry returns an option that makes mutations retriable on gateway errors
+// and network timeouts. Every mutation is then sent with a transaction ID (generated
+// if not set with MutationBuilder.TransactionID), which is reused on each attempt so
+// that the server can deduplicate a mutation that has already been applied.
+func WithMutationRetry(b bool) Option {
+	return func(c *Client) { c.retryMutation = b }
+}
+
+// WithUserAgent returns an option that replaces the default user agent, so that
+// applications can identify themselves to the API.
+func WithUserAgent(ua string) Option {
+	return func(c *Client) { c.userAgent = ua }
+}
+
+// ExceedPolicy determines what happens to a query whose GET request URL would be longer than
+// the maximum set with WithMaxQueryLength.
+type ExceedPolicy int
+
+const (
+	// ExceedPOST sends the query as a POST request instead. This is the default. Note that
+	// POST requests are not cached by the API CDN.
+	ExceedPOST ExceedPolicy = iota
+
+	// ExceedError fails the query with a *QueryLengthError, without sending it.
+	ExceedError
+
+	// ExceedTruncate is like ExceedError, but the error also holds the start of the query,
+	// truncated to the maximum length, so that it can be logged safely. The query itself is
+	// never sent truncated, since that would change its meaning.
+	ExceedTruncate
+)
+
+// WithMaxQueryLength returns an option that sets the maximum length of GET request URLs,
+// and what happens to queries that exceed it. By default, the maximum is 1024 bytes and
+// longer queries are sent as POST requests. GetDocuments always fetches a list of IDs too
+// long for the maximum with a query sent as a POST request, whatever the policy, since the
+// documents API is not cached by the CDN either. Other requests that exceed the maximum
+// fail.
+func WithMaxQueryLength(n int, onExceed ExceedPolicy) Option {
+	return func(c *Client) {
+		c.maxQueryLen = n
+		c.exceedPolicy = onExceed
+	}
+}
+
+// WithResultTransformer returns an option that adds a function that transforms the result of
+// every query performed with QueryBuilder.Do, such as to normalize dates or rewrite asset
+// URLs. Transformers run in the order they were added, each receiving the result of the
+// previous one. If a transformer returns an error, the query fails with it.
+func WithResultTransformer(fn func(*json.RawMessage) (*json.RawMessage, error)) Option {
+	return func(c *Client) { c.transformers = append(c.transformers, fn) }
+}
+
+// WithIDGenerator returns an option that sets an _id generated by fn on documents passed to
+// MutationBuilder.Create and CreateOrReplace that do not have one, rather than leaving it
+// to the API. Client-controlled IDs, such as UUIDs or slugs, make documents predictable to
+// reference, and creates idempotent when the ID is derived from the content. An explicit
+// _id is never overwritten.
+func WithIDGenerator(fn func() string) Option {
+	return func(c *Client) { c.idGenerator = fn }
+}
+
+// WithPerspective returns an option for setting the default perspective of all queries,
+// such as "published" or "previewDrafts". It can be overridden per query with
+// QueryBuilder.Perspective.
+func WithPerspective(p string) Option {
+	return func(c *Client) { c.perspective = p }
+}
+
 // WithTag returns an option for setting the default tag to set on all requests.
 func WithTag(t string) Option {
 	return func(c *Client) { c.tag = t }
@@ -164,16 +412,16 @@ func (v Version) NewClient(projectID, dataset string, opts ...Option) (*Client,
 		return nil, errors.New("dataset must be set")
 	}
 
-	baseAPIURL := fmt.Sprintf("%s.%s", projectID, APIHost)
 	c := Client{
 		backoff:    backoff.Backoff{Jitter: true},
+		retryMax:   -1,
 		hc:         http.DefaultClient,
 		projectID:  projectID,
 		dataset:    dataset,
 		apiVersion: v,
 		baseAPIURL: url.URL{
 			Scheme: "https",
-			Host:   baseAPIURL,
+			Host:   fmt.Sprintf("%s.%s", projectID, APIHost),
 			Path:   fmt.Sprintf("/v%s", v.String()),
 		},
 	}
@@ -182,17 +430,121 @@ func (v Version) NewClient(projectID, dataset string, opts ...Option) (*Client,
 		opt(&c)
 	}
 
+	if err := c.init(); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// Clone returns a copy of the client with the given options applied on top of its current
+// configuration, such as a different token or dataset. The copy shares the HTTP client, and
+// thus its connection pool, with the original, unless the options replace it. Changes to
+// the copy do not affect the original.
+func (c *Client) Clone(opts ...Option) (*Client, error) {
+	clone := *c
+	clone.customHeaders = c.customHeaders.Clone()
+	clone.fixedHeaders = c.fixedHeaders.Clone()
+	clone.transformers = c.transformers[:len(c.transformers):len(c.transformers)]
+	for _, opt := range opts {
+		opt(&clone)
+	}
+
+	// An HTTP client built for WithProxy and WithInsecureSkipVerify is shared until they
+	// change, when it is rebuilt. If it is replaced with WithHTTPClient, the settings it was
+	// built for no longer apply.
+	sameTransport := clone.proxyURL == c.proxyURL && clone.insecureTLS == c.insecureTLS
+	switch {
+	case clone.hc != c.hc && sameTransport:
+		clone.proxyURL, clone.insecureTLS = "", false
+	case clone.hc == c.hc && c.hcBuilt && !sameTransport:
+		clone.hc, clone.hcBuilt = http.DefaultClient, false
+	}
+
+	if err := clone.init(); err != nil {
+		return nil, err
+	}
+	return &clone, nil
+}
+
+// buildsHTTPClient reports whether the HTTP client is built from the client's options, rather
+// than given with WithHTTPClient.
+func (c *Client) buildsHTTPClient() bool {
+	return c.proxyURL != "" || c.insecureTLS
+}
+
+// buildHTTPClient builds an HTTP client with a copy of the default transport, configured for
+// WithProxy and WithInsecureSkipVerify.
+func (c *Client) buildHTTPClient() error {
+	if c.hc != http.DefaultClient {
+		if c.proxyURL != "" {
+			return errors.New("proxy cannot be combined with a custom HTTP client")
+		}
+		return errors.New("insecure TLS cannot be combined with a custom HTTP client")
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if c.proxyURL != "" {
+		proxyURL, err := url.Parse(c.proxyURL)
+		if err != nil {
+			return fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+	if c.insecureTLS {
+		if transport.TLSClientConfig == nil {
+			transport.TLSClientConfig = &tls.Config{}
+		}
+		transport.TLSClientConfig.InsecureSkipVerify = true
+	}
+	c.hc, c.hcBuilt = &http.Client{Transport: transport}, true
+	return nil
+}
+
+// init derives the rest of the client's state from its options.
+func (c *Client) init() error {
+	if c.dataset == "" {
+		return errors.New("dataset must be set")
+	}
+
+	if c.buildsHTTPClient() && !c.hcBuilt {
+		if err := c.buildHTTPClient(); err != nil {
+			return err
+		}
+	}
+
+	c.loader = nil
+	if c.loaderWindow > 0 {
+		c.loader = newDocumentLoader(c, c.loaderWindow)
+	}
+
+	c.baseAPIURL.Path = fmt.Sprintf("/v%s", c.apiVersion.String())
+	if prefix := strings.Trim(c.basePath, "/"); prefix != "" {
+		c.baseAPIURL.Path = "/" + prefix + c.baseAPIURL.Path
+	}
+
 	c.baseQueryURL = c.baseAPIURL
 	// Only use APICDN if useCDN=true and API host has not been updated by options.
-	if c.useCDN && c.baseAPIURL.Host == baseAPIURL {
-		c.baseQueryURL.Host = fmt.Sprintf("%s.%s", projectID, APICDNHost)
+	if c.useCDN && c.baseAPIURL.Host == fmt.Sprintf("%s.%s", c.projectID, APIHost) {
+		c.baseQueryURL.Host = fmt.Sprintf("%s.%s", c.projectID, APICDNHost)
+	}
+
+	if err := c.initProtocols(); err != nil {
+		return err
 	}
 
 	setDefaultHeaders := func(r *requests.Request) {
-		r.Header("user-agent", "Sanity Go client/"+runtime.Version())
+		userAgent := c.userAgent
+		if userAgent == "" {
+			userAgent = "Sanity Go client/" + runtime.Version()
+		}
+		r.Header("user-agent", userAgent)
 		if c.token != "" {
 			r.Header("authorization", "Bearer "+c.token)
 		}
+		if c.projHeaders {
+			r.Header("X-Sanity-Project-ID", c.projectID)
+			r.Header("X-Sanity-Dataset", c.dataset)
+		}
 	}
 
 	c.setHeaders = func(r *requests.Request) {
@@ -202,58 +554,220 @@ func (v Version) NewClient(projectID, dataset string, opts ...Option) (*Client,
 				r.Header(key, value)
 			}
 		}
+		for key := range c.fixedHeaders {
+			r.SetHeader(key, c.fixedHeaders.Get(key))
+		}
 	}
 
-	return &c, nil
+	return nil
 }
 
 func (c *Client) do(ctx context.Context, r *requests.Request, dest interface{}) (*http.Response, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := c.send(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	dec := json.NewDecoder(resp.Body)
+	if c.useNumber {
+		dec.UseNumber()
+	}
+	return resp, dec.Decode(dest)
+}
+
+// doPreserved is like do, but reads the whole response body before decoding it, and replaces
+// the body of the returned response with a reader over the read bytes, so that the caller
+// can still read it.
+func (c *Client) doPreserved(ctx context.Context, r *requests.Request, dest interface{}) (*http.Response, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := c.send(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("reading response body: %w", err)
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(b))
+
+	return resp, unmarshalJSON(b, dest, c.useNumber)
+}
+
+// send performs the request, retrying as configured, and returns the first successful
+// response. The caller is responsible for closing the response body.
+func (c *Client) send(ctx context.Context, r *requests.Request) (*http.Response, error) {
+	r.InheritTag(tagFromContext(ctx))
 	req, err := r.HTTPRequest()
 	if err != nil {
 		return nil, err
 	}
 
+	if id := correlationIDFromContext(ctx); id != "" {
+		header := c.corrHeader
+		if header == "" {
+			header = DefaultCorrelationHeader
+		}
+		req.Header.Set(header, id)
+	}
+
 	// Workaround for setting custom host header which is overridden after req.Header.Add()
 	// See: https://github.com/golang/go/issues/29865
 	if host := req.Header.Get("host"); host != "" {
 		req.Host = host
 	}
 
-	if req.Method == http.MethodGet && len(r.EncodeURL()) > maxGETRequestURLLength {
+	if req.Method == http.MethodGet && len(r.EncodeURL()) > c.maxGETLength() {
 		return nil, errors.New("max URL length exceeded in GET request")
 	}
 
 	req = req.WithContext(ctx)
+	reqBody, err := c.tapRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
 	bckoff := c.backoff
-	for {
-		resp, err := c.hc.Do(req)
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			if req.Body, err = req.GetBody(); err != nil {
+				return nil, fmt.Errorf("[%s %s] failed to rewind body: %w", req.Method, req.URL.String(), err)
+			}
+		}
+
+		attemptReq := req
+		var trace *requestTrace
+		if c.httpTrace != nil {
+			trace = newRequestTrace(req, attempt+1, c.httpTrace)
+			attemptReq = req.WithContext(trace.withContext(req.Context()))
+		}
+
+		start := time.Now()
+		resp, err := c.hc.Do(attemptReq)
+		c.logAttempt(ctx, req, resp, err, attempt, time.Since(start))
+		if trace != nil {
+			if err != nil {
+				trace.finish(err)
+			} else {
+				resp.Body = &traceBody{ReadCloser: resp.Body, trace: trace}
+			}
+		}
+		if err == nil && c.wireTap != nil {
+			c.tapResponse(reqBody, resp)
+		}
 		if err != nil {
+			if r.IsIdempotent() && isErrorRetriable(err) && ctx.Err() == nil && c.canRetry(attempt) {
+				if err := c.retryAfter(ctx, &bckoff, attempt, err, nil); err != nil {
+					return nil, fmt.Errorf("[%s %s] failed: %w", req.Method, req.URL.String(), err)
+				}
+				continue
+			}
 			return nil, fmt.Errorf("[%s %s] failed: %w", req.Method, req.URL.String(), err)
 		}
 
-		defer func() {
-			_ = resp.Body.Close()
-		}()
-
 		if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
-			return resp, json.NewDecoder(resp.Body).Decode(dest)
+			return resp, nil
 		}
 
-		if !isMethodRetriable(req.Method) || !isStatusCodeRetriable(resp.StatusCode) {
-			return nil, c.handleErrorResponse(req, resp)
+		reqErr := c.handleErrorResponse(req, resp, r.DatasetName())
+		_ = resp.Body.Close()
+
+		var retriable bool
+		switch {
+		case reqErr.isQuotaExceeded():
+			return nil, &QuotaError{RequestError: reqErr}
+		case resp.StatusCode == http.StatusTooManyRequests:
+			// A rate-limited request is rejected before it is processed, so it is safe to
+			// retry whatever its method.
+			retriable = true
+		case r.IsIdempotent():
+			retriable = isIdempotentStatusCodeRetriable(resp.StatusCode)
+		default:
+			retriable = isMethodRetriable(req.Method) && isStatusCodeRetriable(resp.StatusCode)
 		}
 
-		_ = resp.Body.Close()
+		if !retriable || !c.canRetry(attempt) {
+			return nil, reqErr
+		}
 
-		if c.callbacks.OnErrorWillRetry != nil {
-			c.callbacks.OnErrorWillRetry(err)
+		if err := c.retryAfter(ctx, &bckoff, attempt, reqErr, resp); err != nil {
+			return nil, fmt.Errorf("[%s %s] failed: %w", req.Method, req.URL.String(), err)
 		}
+	}
+}
+
+// retryAfter notifies the retry callbacks about the failed attempt and waits for the
+// backoff delay before the next one.
+func (c *Client) retryAfter(ctx context.Context, b *backoff.Backoff, attempt int, err error, resp *http.Response) error {
+	delay := b.Duration()
 
-		time.Sleep(bckoff.Duration())
+	if c.callbacks.OnErrorWillRetry != nil {
+		c.callbacks.OnErrorWillRetry(err)
+	}
+	if c.callbacks.OnRetry != nil {
+		c.callbacks.OnRetry(attempt+1, delay, resp)
 	}
+	if c.logger != nil {
+		c.logger.LogAttrs(ctx, slog.LevelWarn, "retrying request",
+			slog.Int("retry", attempt+1),
+			slog.Duration("delay", delay),
+			slog.String("error", err.Error()))
+	}
+
+	return sleep(ctx, delay)
 }
 
-func (c *Client) handleErrorResponse(req *http.Request, resp *http.Response) error {
+// logAttempt logs a single request attempt, if a logger is set.
+func (c *Client) logAttempt(ctx context.Context, req *http.Request, resp *http.Response, err error, attempt int, d time.Duration) {
+	if c.logger == nil {
+		return
+	}
+
+	attrs := []slog.Attr{
+		slog.String("method", req.Method),
+		slog.String("url", req.URL.String()),
+		slog.Duration("duration", d),
+		slog.Int("attempt", attempt+1),
+	}
+	if resp != nil {
+		attrs = append(attrs, slog.Int("status", resp.StatusCode))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", err.Error()))
+	}
+	c.logger.LogAttrs(ctx, slog.LevelDebug, "request", attrs...)
+}
+
+// withTimeout applies the default timeout to ctx if it has no deadline.
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || c.timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+// canRetry reports whether another attempt may be made after the given (zero-based) attempt.
+func (c *Client) canRetry(attempt int) bool {
+	return c.retryMax < 0 || attempt < c.retryMax
+}
+
+// handleErrorResponse returns the error for a failed response to a request for the given
+// dataset, or for the client's dataset if empty.
+func (c *Client) handleErrorResponse(req *http.Request, resp *http.Response, dataset string) *RequestError {
+	if dataset == "" {
+		dataset = c.dataset
+	}
+
 	body := []byte("[no response body]")
 
 	if resp.Body != nil {
@@ -264,22 +778,50 @@ func (c *Client) handleErrorResponse(req *http.Request, resp *http.Response) err
 	}
 
 	return &RequestError{
-		Request:  req,
-		Response: resp,
-		Body:     body,
+		Request:   req,
+		Response:  resp,
+		Body:      body,
+		ProjectID: c.projectID,
+		Dataset:   dataset,
+	}
+}
+
+// forDataset marks a request as targeting the given dataset, which may differ from the
+// client's, so that the X-Sanity-Dataset header and errors name the right dataset.
+func (c *Client) forDataset(r *requests.Request, dataset string) *requests.Request {
+	if dataset == c.dataset {
+		return r
+	}
+	if c.projHeaders {
+		r.SetHeader("X-Sanity-Dataset", dataset)
 	}
+	return r.Dataset(dataset)
 }
 
 func (c *Client) newAPIRequest() *requests.Request {
-	r := requests.New(c.baseAPIURL)
+	r := requests.New(c.baseAPIURL).CleanPath()
 	c.setHeaders(r)
+	if c.gzipMinSize > 0 {
+		r.Gzip(c.gzipMinSize)
+	}
 	return r
 }
 
 func (c *Client) newQueryRequest() *requests.Request {
-	r := requests.New(c.baseQueryURL)
+	r := requests.New(c.baseQueryURL).CleanPath()
 	c.setHeaders(r)
+	if c.gzipMinSize > 0 {
+		r.Gzip(c.gzipMinSize)
+	}
 	return r
 }
 
 const maxGETRequestURLLength = 1024
+
+// maxGETLength returns the maximum length of GET request URLs.
+func (c *Client) maxGETLength() int {
+	if c.maxQueryLen > 0 {
+		return c.maxQueryLen
+	}
+	return maxGETRequestURLLength
+}
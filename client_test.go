@@ -1,14 +1,31 @@
 package sanity_test
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"net/url"
+	"runtime"
 	"testing"
+	"time"
 
+	"github.com/go-chi/chi"
+	"github.com/jpillora/backoff"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	sanity "github.com/sanity-io/client-go"
+	"github.com/sanity-io/client-go/api"
 )
 
 func TestAuthorization(t *testing.T) {
@@ -48,6 +65,740 @@ func TestCustomHeaders(t *testing.T) {
 	)
 }
 
+func TestCustomHeaders_replace(t *testing.T) {
+	withSuite(t, func(s *Suite) {
+		s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, []string{"application/vnd.sanity+json"}, r.Header.Values("accept"))
+			assert.Equal(t, []string{"qux"}, r.Header.Values("foo"))
+
+			_, err := w.Write([]byte("{}"))
+			assert.NoError(t, err)
+		})
+
+		_, err := s.client.Query("*").Do(context.Background())
+		require.NoError(t, err)
+	},
+		sanity.WithHTTPHeader("foo", "bar"),
+		sanity.WithHTTPHeaderReplace("foo", "qux"),
+		sanity.WithHTTPHeaderReplace("accept", "application/vnd.sanity+json"),
+	)
+}
+
+func TestProjectHeaders(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		enable  bool
+		project string
+		dataset string
+	}{
+		{desc: "enabled", enable: true, project: "myProject", dataset: "myDataset"},
+		{desc: "disabled by default"},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			withSuite(t, func(s *Suite) {
+				s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+					assert.Equal(t, tc.project, r.Header.Get("X-Sanity-Project-ID"))
+					assert.Equal(t, tc.dataset, r.Header.Get("X-Sanity-Dataset"))
+
+					_, err := w.Write(mustJSONBytes(&api.MutateResponse{}))
+					assert.NoError(t, err)
+				})
+
+				_, err := s.client.Mutate().Delete("doc1").Do(context.Background())
+				require.NoError(t, err)
+			}, sanity.WithProjectHeaders(tc.enable))
+		})
+	}
+}
+
+func TestBaseURLPath(t *testing.T) {
+	for _, prefix := range []string{"/gateway/sanity", "gateway/sanity/", "/gateway/sanity/"} {
+		prefix := prefix
+		t.Run(prefix, func(t *testing.T) {
+			withSuite(t, func(s *Suite) {
+				s.mux.Get("/gateway/sanity/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+					_, err := w.Write(mustJSONBytes(&api.QueryResponse{Result: mustJSONMsg(1)}))
+					assert.NoError(t, err)
+				})
+				s.mux.Post("/gateway/sanity/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+					_, err := w.Write(mustJSONBytes(&api.MutateResponse{}))
+					assert.NoError(t, err)
+				})
+
+				_, err := s.client.Query("*").Do(context.Background())
+				require.NoError(t, err)
+				_, err = s.client.Mutate().Delete("doc1").Do(context.Background())
+				require.NoError(t, err)
+			}, sanity.WithBaseURLPath(prefix))
+		})
+	}
+}
+
+func TestCorrelationID(t *testing.T) {
+	for _, tc := range []struct {
+		desc   string
+		opts   []sanity.Option
+		header string
+	}{
+		{desc: "default header", header: "X-Correlation-ID"},
+		{desc: "custom header", opts: []sanity.Option{sanity.WithCorrelationHeader("X-Request-ID")}, header: "X-Request-ID"},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			withSuite(t, func(s *Suite) {
+				var got []string
+				s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+					got = append(got, r.Header.Get(tc.header))
+					_, err := w.Write([]byte("{}"))
+					assert.NoError(t, err)
+				})
+
+				ctx := context.WithValue(context.Background(), sanity.CorrelationIDKey, "abc-123")
+				_, err := s.client.Query("*").Do(ctx)
+				require.NoError(t, err)
+
+				_, err = s.client.Query("*").Do(context.Background())
+				require.NoError(t, err)
+
+				assert.Equal(t, []string{"abc-123", ""}, got)
+			}, tc.opts...)
+		})
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	t.Run("can be set", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "my-cms-sync/2.1", r.Header.Get("User-Agent"))
+
+				_, err := w.Write([]byte("{}"))
+				assert.NoError(t, err)
+			})
+
+			_, err := s.client.Query("*").Do(context.Background())
+			require.NoError(t, err)
+		},
+			sanity.WithUserAgent("my-cms-sync/2.1"),
+		)
+	})
+
+	t.Run("has a default", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "Sanity Go client/"+runtime.Version(), r.Header.Get("User-Agent"))
+
+				_, err := w.Write([]byte("{}"))
+				assert.NoError(t, err)
+			})
+
+			_, err := s.client.Query("*").Do(context.Background())
+			require.NoError(t, err)
+		})
+	})
+}
+
+func TestRetryMax(t *testing.T) {
+	for _, tc := range []struct {
+		desc      string
+		retryMax  int
+		wantCalls int
+	}{
+		{"no retries", 0, 1},
+		{"two retries", 2, 3},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			withSuite(t, func(s *Suite) {
+				calls := 0
+				s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+					calls++
+					w.WriteHeader(http.StatusServiceUnavailable)
+					_, err := w.Write([]byte("unavailable"))
+					assert.NoError(t, err)
+				})
+
+				_, err := s.client.Query("*").Do(context.Background())
+				require.Error(t, err)
+
+				var reqErr *sanity.RequestError
+				require.True(t, errors.As(err, &reqErr))
+				assert.Equal(t, http.StatusServiceUnavailable, reqErr.Response.StatusCode)
+				assert.Equal(t, "unavailable", string(reqErr.Body))
+				assert.Equal(t, tc.wantCalls, calls)
+			},
+				sanity.WithRetryMax(tc.retryMax),
+				sanity.WithBackoff(backoff.Backoff{Min: time.Millisecond, Max: time.Millisecond}),
+			)
+		})
+	}
+
+	t.Run("succeeds within limit", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			calls := 0
+			s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if calls < 3 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+				_, err := w.Write([]byte("{}"))
+				assert.NoError(t, err)
+			})
+
+			_, err := s.client.Query("*").Do(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, 3, calls)
+		},
+			sanity.WithRetryMax(2),
+			sanity.WithBackoff(backoff.Backoff{Min: time.Millisecond, Max: time.Millisecond}),
+		)
+	})
+}
+
+func TestRetryDelays(t *testing.T) {
+	minDelay, maxDelay := 2*time.Millisecond, 8*time.Millisecond
+
+	var delays []time.Duration
+	withSuite(t, func(s *Suite) {
+		calls := 0
+		s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+			if calls++; calls <= 6 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_, err := w.Write([]byte("{}"))
+			assert.NoError(t, err)
+		})
+
+		_, err := s.client.Query("*").Do(context.Background())
+		require.NoError(t, err)
+	},
+		sanity.WithRetryDelays(minDelay, maxDelay, 3),
+		sanity.WithCallbacks(sanity.Callbacks{
+			OnRetry: func(attempt int, delay time.Duration, resp *http.Response) {
+				delays = append(delays, delay)
+			},
+		}),
+	)
+
+	require.Len(t, delays, 6)
+	for i, d := range delays {
+		assert.True(t, d >= minDelay && d <= maxDelay, "delay %d is %s, outside [%s, %s]", i, d, minDelay, maxDelay)
+	}
+	// With a factor of 3, the bound reaches the maximum by the third retry.
+	assert.True(t, delays[0] <= 3*minDelay, "first delay %s exceeds %s", delays[0], 3*minDelay)
+}
+
+func TestOnRetry(t *testing.T) {
+	var attempts []int
+	var delays []time.Duration
+	var statuses []int
+	var errs []error
+
+	withSuite(t, func(s *Suite) {
+		calls := 0
+		s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_, err := w.Write([]byte("{}"))
+			assert.NoError(t, err)
+		})
+
+		_, err := s.client.Query("*").Do(context.Background())
+		require.NoError(t, err)
+	},
+		sanity.WithBackoff(backoff.Backoff{Min: time.Millisecond, Max: time.Millisecond}),
+		sanity.WithCallbacks(sanity.Callbacks{
+			OnErrorWillRetry: func(err error) {
+				errs = append(errs, err)
+			},
+			OnRetry: func(attempt int, delay time.Duration, resp *http.Response) {
+				attempts = append(attempts, attempt)
+				delays = append(delays, delay)
+				require.NotNil(t, resp)
+				statuses = append(statuses, resp.StatusCode)
+			},
+		}),
+	)
+
+	assert.Equal(t, []int{1, 2}, attempts)
+	assert.Equal(t, []time.Duration{time.Millisecond, time.Millisecond}, delays)
+	assert.Equal(t, []int{http.StatusServiceUnavailable, http.StatusServiceUnavailable}, statuses)
+
+	require.Len(t, errs, 2)
+	for _, err := range errs {
+		var reqErr *sanity.RequestError
+		require.True(t, errors.As(err, &reqErr))
+		assert.Equal(t, http.StatusServiceUnavailable, reqErr.StatusCode())
+	}
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	withSuite(t, func(s *Suite) {
+		calls := 0
+		s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			if calls == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_, err := w.Write([]byte("{}"))
+			assert.NoError(t, err)
+		})
+
+		_, err := s.client.Query("*").Do(context.Background())
+		require.NoError(t, err)
+	},
+		sanity.WithLogger(logger),
+		sanity.WithBackoff(backoff.Backoff{Min: time.Millisecond, Max: time.Millisecond}),
+	)
+
+	var records []map[string]interface{}
+	dec := json.NewDecoder(&buf)
+	for dec.More() {
+		var record map[string]interface{}
+		require.NoError(t, dec.Decode(&record))
+		records = append(records, record)
+	}
+	require.Len(t, records, 3)
+
+	assert.Equal(t, "DEBUG", records[0]["level"])
+	assert.Equal(t, "request", records[0]["msg"])
+	assert.Equal(t, "GET", records[0]["method"])
+	assert.Contains(t, records[0]["url"], "/v1/data/query/myDataset")
+	assert.Equal(t, float64(http.StatusServiceUnavailable), records[0]["status"])
+	assert.Equal(t, float64(1), records[0]["attempt"])
+	assert.Contains(t, records[0], "duration")
+
+	assert.Equal(t, "WARN", records[1]["level"])
+	assert.Equal(t, "retrying request", records[1]["msg"])
+	assert.Equal(t, float64(1), records[1]["retry"])
+
+	assert.Equal(t, float64(http.StatusOK), records[2]["status"])
+	assert.Equal(t, float64(2), records[2]["attempt"])
+}
+
+func TestClone(t *testing.T) {
+	withSuite(t, func(s *Suite) {
+		s.mux.Get("/v1/data/query/{dataset}", func(w http.ResponseWriter, r *http.Request) {
+			dataset := chi.URLParam(r, "dataset")
+			switch dataset {
+			case "myDataset":
+				assert.Equal(t, "Bearer parent", r.Header.Get("Authorization"))
+				assert.Equal(t, []string{"parent"}, r.Header.Values("X-Test"))
+			case "other":
+				assert.Equal(t, "Bearer clone", r.Header.Get("Authorization"))
+				assert.Equal(t, []string{"parent", "clone"}, r.Header.Values("X-Test"))
+			default:
+				t.Errorf("unexpected dataset %q", dataset)
+			}
+
+			_, err := w.Write([]byte("{}"))
+			assert.NoError(t, err)
+		})
+
+		clone, err := s.client.Clone(
+			sanity.WithToken("clone"),
+			sanity.WithDataset("other"),
+			sanity.WithHTTPHeader("X-Test", "clone"))
+		require.NoError(t, err)
+
+		_, err = clone.Query("*").Do(context.Background())
+		require.NoError(t, err)
+		_, err = s.client.Query("*").Do(context.Background())
+		require.NoError(t, err)
+
+		assert.Equal(t, "other", clone.Config().Dataset)
+		assert.Equal(t, "myDataset", s.client.Config().Dataset)
+
+		_, err = s.client.Clone(sanity.WithDataset(""))
+		require.Error(t, err)
+	},
+		sanity.WithToken("parent"),
+		sanity.WithHTTPHeader("X-Test", "parent"),
+	)
+}
+
+func TestTimeout(t *testing.T) {
+	t.Run("applies when context has no deadline", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				select {
+				case <-r.Context().Done():
+				case <-time.After(time.Second):
+				}
+			})
+
+			start := time.Now()
+			_, err := s.client.Query("*").Do(context.Background())
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, context.DeadlineExceeded))
+			assert.Less(t, int64(time.Since(start)), int64(500*time.Millisecond))
+		}, sanity.WithTimeout(20*time.Millisecond))
+	})
+
+	t.Run("covers retries", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			})
+
+			_, err := s.client.Query("*").Do(context.Background())
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, context.DeadlineExceeded))
+		},
+			sanity.WithTimeout(20*time.Millisecond),
+			sanity.WithBackoff(backoff.Backoff{Min: 5 * time.Millisecond, Max: 5 * time.Millisecond}),
+		)
+	})
+
+	t.Run("does not shorten caller deadline", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				time.Sleep(50 * time.Millisecond)
+				_, err := w.Write([]byte("{}"))
+				assert.NoError(t, err)
+			})
+
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			_, err := s.client.Query("*").Do(ctx)
+			require.NoError(t, err)
+		}, sanity.WithTimeout(10*time.Millisecond))
+	})
+}
+
+func TestProxy(t *testing.T) {
+	t.Run("sends requests through proxy", func(t *testing.T) {
+		var got []string
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = append(got, r.Method+" "+r.URL.String())
+			_, err := w.Write([]byte("{}"))
+			assert.NoError(t, err)
+		}))
+		defer proxy.Close()
+
+		c, err := sanity.VersionV1.NewClient("myProject", "myDataset",
+			sanity.WithHTTPHost("http", "api.example.test"),
+			sanity.WithProxy(proxy.URL))
+		require.NoError(t, err)
+
+		_, err = c.Query("*").Do(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, []string{"GET http://api.example.test/v1/data/query/myDataset?query=%2A"}, got)
+	})
+
+	t.Run("rejects custom HTTP client", func(t *testing.T) {
+		_, err := sanity.VersionV1.NewClient("myProject", "myDataset",
+			sanity.WithHTTPClient(&http.Client{}),
+			sanity.WithProxy("http://proxy.example.test"))
+		require.Error(t, err)
+	})
+
+	t.Run("clone with custom HTTP client drops proxy", func(t *testing.T) {
+		proxied := 0
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			proxied++
+		}))
+		defer proxy.Close()
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, err := w.Write([]byte("{}"))
+			assert.NoError(t, err)
+		}))
+		defer server.Close()
+		serverURL, err := url.Parse(server.URL)
+		require.NoError(t, err)
+
+		c, err := sanity.VersionV1.NewClient("myProject", "myDataset",
+			sanity.WithHTTPHost("http", serverURL.Host),
+			sanity.WithProxy(proxy.URL))
+		require.NoError(t, err)
+
+		clone, err := c.Clone(sanity.WithHTTPClient(server.Client()))
+		require.NoError(t, err)
+		_, err = clone.Query("*").Do(context.Background())
+		require.NoError(t, err)
+		assert.Zero(t, proxied)
+
+		// The clone no longer has a proxy to rebuild its HTTP client with, so an option that
+		// requires building one conflicts with the custom client, as with NewClient.
+		_, err = clone.Clone(sanity.WithInsecureSkipVerify())
+		require.Error(t, err)
+	})
+
+	t.Run("rejects invalid URL", func(t *testing.T) {
+		_, err := sanity.VersionV1.NewClient("myProject", "myDataset",
+			sanity.WithProxy("http://[::1"))
+		require.Error(t, err)
+	})
+}
+
+func TestInsecureSkipVerify(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write(mustJSONBytes(&api.QueryResponse{Result: mustJSONMsg("ok")}))
+		assert.NoError(t, err)
+	}))
+	defer server.Close()
+
+	serverURL, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	query := func(c *sanity.Client) error {
+		_, err := c.Query("*").Do(context.Background())
+		return err
+	}
+
+	t.Run("rejects self-signed certificate by default", func(t *testing.T) {
+		c, err := sanity.VersionV1.NewClient("myProject", "myDataset",
+			sanity.WithHTTPHost("https", serverURL.Host))
+		require.NoError(t, err)
+		require.Error(t, query(c))
+	})
+
+	t.Run("accepts self-signed certificate", func(t *testing.T) {
+		c, err := sanity.VersionV1.NewClient("myProject", "myDataset",
+			sanity.WithHTTPHost("https", serverURL.Host),
+			sanity.WithInsecureSkipVerify())
+		require.NoError(t, err)
+		require.NoError(t, query(c))
+
+		clone, err := c.Clone(sanity.WithDataset("other"))
+		require.NoError(t, err)
+		require.NoError(t, query(clone))
+	})
+
+	t.Run("combined with proxy", func(t *testing.T) {
+		var tunneled []string
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !assert.Equal(t, http.MethodConnect, r.Method) {
+				return
+			}
+			tunneled = append(tunneled, r.Host)
+
+			upstream, err := net.Dial("tcp", r.Host)
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer upstream.Close()
+
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if !assert.NoError(t, err) {
+				return
+			}
+			defer conn.Close()
+
+			_, err = conn.Write([]byte("HTTP/1.1 200 OK\r\n\r\n"))
+			assert.NoError(t, err)
+			go func() {
+				_, _ = io.Copy(upstream, conn)
+			}()
+			_, _ = io.Copy(conn, upstream)
+		}))
+		defer proxy.Close()
+
+		c, err := sanity.VersionV1.NewClient("myProject", "myDataset",
+			sanity.WithHTTPHost("https", serverURL.Host),
+			sanity.WithProxy(proxy.URL),
+			sanity.WithInsecureSkipVerify())
+		require.NoError(t, err)
+		require.NoError(t, query(c))
+		assert.Equal(t, []string{serverURL.Host}, tunneled)
+
+		// Enabling it on a clone keeps the proxy.
+		c, err = sanity.VersionV1.NewClient("myProject", "myDataset",
+			sanity.WithHTTPHost("https", serverURL.Host),
+			sanity.WithProxy(proxy.URL))
+		require.NoError(t, err)
+		clone, err := c.Clone(sanity.WithInsecureSkipVerify())
+		require.NoError(t, err)
+		require.NoError(t, query(clone))
+		assert.Len(t, tunneled, 2)
+	})
+
+	t.Run("rejects custom HTTP client", func(t *testing.T) {
+		_, err := sanity.VersionV1.NewClient("myProject", "myDataset",
+			sanity.WithHTTPClient(&http.Client{}),
+			sanity.WithInsecureSkipVerify())
+		require.Error(t, err)
+	})
+}
+
+func TestRequestGzip(t *testing.T) {
+	withSuite(t, func(s *Suite) {
+		var ids []string
+		for i := 0; i < 100; i++ {
+			ids = append(ids, fmt.Sprintf("doc%d", i))
+		}
+
+		s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+
+			zr, err := gzip.NewReader(r.Body)
+			require.NoError(t, err)
+
+			var req api.MutateRequest
+			require.NoError(t, json.NewDecoder(zr).Decode(&req))
+			assert.Len(t, req.Mutations, len(ids))
+			assert.Equal(t, "doc99", req.Mutations[99].Delete.ID)
+
+			_, err = w.Write(mustJSONBytes(&api.MutateResponse{}))
+			assert.NoError(t, err)
+		})
+
+		mb := s.client.Mutate()
+		for _, id := range ids {
+			mb.Delete(id)
+		}
+		_, err := mb.Do(context.Background())
+		require.NoError(t, err)
+	}, sanity.WithRequestGzipThreshold(1024))
+}
+
+func TestWireTap(t *testing.T) {
+	type capture struct {
+		req, resp string
+	}
+
+	t.Run("captures request and response bodies", func(t *testing.T) {
+		var captured []capture
+		var sent, received []byte
+
+		withSuite(t, func(s *Suite) {
+			s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				var err error
+				sent, err = io.ReadAll(r.Body)
+				require.NoError(t, err)
+
+				received = mustJSONBytes(&api.MutateResponse{TransactionID: "tx1"})
+				_, err = w.Write(received)
+				assert.NoError(t, err)
+			})
+
+			_, err := s.client.Mutate().Delete("doc1").Do(context.Background())
+			require.NoError(t, err)
+		}, sanity.WithWireTap(func(reqBytes, respBytes []byte) {
+			captured = append(captured, capture{string(reqBytes), string(respBytes)})
+		}))
+
+		require.Len(t, captured, 1)
+		assert.Equal(t, string(sent), captured[0].req)
+		assert.Equal(t, string(received), captured[0].resp)
+	})
+
+	t.Run("captures each attempt", func(t *testing.T) {
+		var captured []capture
+		calls := 0
+
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				if calls == 1 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					_, _ = w.Write([]byte(`{"error":"unavailable"}`))
+					return
+				}
+				_, err := w.Write([]byte(`{"result":1}`))
+				assert.NoError(t, err)
+			})
+
+			_, err := s.client.Query("*").Do(context.Background())
+			require.NoError(t, err)
+		},
+			sanity.WithBackoff(backoff.Backoff{Min: time.Millisecond, Max: time.Millisecond}),
+			sanity.WithWireTap(func(reqBytes, respBytes []byte) {
+				captured = append(captured, capture{string(reqBytes), string(respBytes)})
+			}))
+
+		assert.Equal(t, []capture{
+			{"", `{"error":"unavailable"}`},
+			{"", `{"result":1}`},
+		}, captured)
+	})
+}
+
+func TestHTTPTrace(t *testing.T) {
+	withSuite(t, func(s *Suite) {
+		s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(20 * time.Millisecond)
+			w.(http.Flusher).Flush()
+			time.Sleep(10 * time.Millisecond)
+			_, err := w.Write([]byte(`{"result":1}`))
+			assert.NoError(t, err)
+		})
+
+		var timings []*sanity.RequestTimings
+		trace := sanity.WithHTTPTrace(func(rt *sanity.RequestTimings) {
+			timings = append(timings, rt)
+		})
+		client, err := s.client.Clone(trace)
+		require.NoError(t, err)
+
+		for i := 0; i < 2; i++ {
+			_, err := client.Query("*").Do(context.Background())
+			require.NoError(t, err)
+		}
+
+		require.Len(t, timings, 2)
+		first, second := timings[0], timings[1]
+
+		assert.Equal(t, http.MethodGet, first.Method)
+		assert.Equal(t, 1, first.Attempt)
+		assert.Equal(t, 1, second.Attempt)
+		assert.Contains(t, first.URL, "/v1/data/query/myDataset")
+		assert.NoError(t, first.Err)
+
+		assert.False(t, first.ReusedConn)
+		assert.True(t, first.Connect > 0, "connect: %s", first.Connect)
+		assert.Zero(t, first.TLS)
+		assert.True(t, first.TimeToFirstByte >= 20*time.Millisecond, "time to first byte: %s", first.TimeToFirstByte)
+		assert.True(t, first.TimeToFirstByte > first.Connect)
+		assert.True(t, first.BodyRead >= 10*time.Millisecond, "body read: %s", first.BodyRead)
+		assert.True(t, first.Total >= first.TimeToFirstByte+first.BodyRead, "total: %s", first.Total)
+
+		assert.True(t, second.ReusedConn)
+		assert.Zero(t, second.Connect)
+		assert.True(t, second.TimeToFirstByte >= 20*time.Millisecond, "time to first byte: %s", second.TimeToFirstByte)
+	})
+}
+
+func TestHTTPTrace_retries(t *testing.T) {
+	var timings, retries []int
+	withSuite(t, func(s *Suite) {
+		calls := 0
+		s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+			if calls++; calls == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			_, err := w.Write([]byte(`{"result":1}`))
+			assert.NoError(t, err)
+		})
+
+		_, err := s.client.Query("*").Do(context.Background())
+		require.NoError(t, err)
+	},
+		sanity.WithBackoff(backoff.Backoff{Min: time.Millisecond, Max: time.Millisecond}),
+		sanity.WithHTTPTrace(func(rt *sanity.RequestTimings) {
+			timings = append(timings, rt.Attempt)
+		}),
+		sanity.WithCallbacks(sanity.Callbacks{OnRetry: func(attempt int, _ time.Duration, _ *http.Response) {
+			retries = append(retries, attempt)
+		}}))
+
+	assert.Equal(t, []int{1, 2}, timings)
+	assert.Equal(t, []int{1}, retries)
+}
+
 func TestVersion_Validate(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -84,3 +835,68 @@ func TestVersion_Validate(t *testing.T) {
 		})
 	}
 }
+
+func TestProtocols(t *testing.T) {
+	mux := chi.NewRouter()
+	mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+		_, err := w.Write(mustJSONBytes(&api.QueryResponse{Result: mustJSONMsg(r.Proto)}))
+		assert.NoError(t, err)
+	})
+
+	server := httptest.NewUnstartedServer(mux)
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	// Route both the API and the CDN host to the test server.
+	transport := server.Client().Transport.(*http.Transport).Clone()
+	transport.TLSClientConfig.InsecureSkipVerify = true
+	transport.DialContext = func(ctx context.Context, network, _ string) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, network, server.Listener.Addr().String())
+	}
+
+	c, err := sanity.VersionV1.NewClient("myProject", "myDataset",
+		sanity.WithHTTPClient(&http.Client{Transport: transport}),
+		sanity.WithProtocols(sanity.ProtocolHTTP1, sanity.ProtocolHTTP2))
+	require.NoError(t, err)
+
+	query := func(t *testing.T, useCDN bool) (string, string) {
+		var negotiated string
+		ctx := httptrace.WithClientTrace(context.Background(), &httptrace.ClientTrace{
+			GotConn: func(info httptrace.GotConnInfo) {
+				if conn, ok := info.Conn.(*tls.Conn); ok {
+					negotiated = conn.ConnectionState().NegotiatedProtocol
+				}
+			},
+		})
+
+		client, err := c.Clone(sanity.WithCDN(useCDN))
+		require.NoError(t, err)
+
+		result, err := client.Query("*").Do(ctx)
+		require.NoError(t, err)
+
+		var proto string
+		require.NoError(t, result.Unmarshal(&proto))
+		return negotiated, proto
+	}
+
+	t.Run("API host", func(t *testing.T) {
+		negotiated, proto := query(t, false)
+		assert.NotEqual(t, "h2", negotiated)
+		assert.Equal(t, "HTTP/1.1", proto)
+	})
+
+	t.Run("CDN host", func(t *testing.T) {
+		negotiated, proto := query(t, true)
+		assert.Equal(t, "h2", negotiated)
+		assert.Equal(t, "HTTP/2.0", proto)
+	})
+
+	t.Run("invalid protocol", func(t *testing.T) {
+		_, err := sanity.VersionV1.NewClient("myProject", "myDataset",
+			sanity.WithProtocols("HTTP/3", ""))
+		require.Error(t, err)
+	})
+}
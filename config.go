@@ -0,0 +1,47 @@
+package sanity
+
+// ClientConfig describes the effective configuration of a client. It is meant for
+// diagnosing misconfiguration, and never contains secrets such as the API token.
+type ClientConfig struct {
+	// APIVersion is the API version.
+	APIVersion Version
+
+	// ProjectID is the project ID.
+	ProjectID string
+
+	// Dataset is the dataset.
+	Dataset string
+
+	// APIHost is the host used for mutations and other API calls.
+	APIHost string
+
+	// QueryHost is the host used for queries. It is the CDN host if the CDN is in use.
+	QueryHost string
+
+	// UseCDN is true if queries go through the Sanity API CDN.
+	UseCDN bool
+
+	// HasToken is true if an API token is set.
+	HasToken bool
+
+	// Tag is the default request tag.
+	Tag string
+
+	// Perspective is the default query perspective.
+	Perspective string
+}
+
+// Config returns the effective configuration of the client.
+func (c *Client) Config() ClientConfig {
+	return ClientConfig{
+		APIVersion:  c.apiVersion,
+		ProjectID:   c.projectID,
+		Dataset:     c.dataset,
+		APIHost:     c.baseAPIURL.Host,
+		QueryHost:   c.baseQueryURL.Host,
+		UseCDN:      c.baseQueryURL.Host != c.baseAPIURL.Host,
+		HasToken:    c.token != "",
+		Tag:         c.tag,
+		Perspective: c.perspective,
+	}
+}
@@ -0,0 +1,48 @@
+package sanity_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sanity "github.com/sanity-io/client-go"
+)
+
+func TestConfig(t *testing.T) {
+	t.Run("reports constructed options", func(t *testing.T) {
+		c, err := sanity.VersionV20210325.NewClient("myProject", "myDataset",
+			sanity.WithCDN(true),
+			sanity.WithToken("secret"),
+			sanity.WithTag("tag"),
+			sanity.WithPerspective("published"))
+		require.NoError(t, err)
+
+		assert.Equal(t, sanity.ClientConfig{
+			APIVersion:  sanity.VersionV20210325,
+			ProjectID:   "myProject",
+			Dataset:     "myDataset",
+			APIHost:     "myProject.api.sanity.io",
+			QueryHost:   "myProject.apicdn.sanity.io",
+			UseCDN:      true,
+			HasToken:    true,
+			Tag:         "tag",
+			Perspective: "published",
+		}, c.Config())
+		assert.NotContains(t, fmt.Sprintf("%+v", c.Config()), "secret")
+	})
+
+	t.Run("reports CDN disabled with custom host", func(t *testing.T) {
+		c, err := sanity.VersionV1.NewClient("myProject", "myDataset",
+			sanity.WithCDN(true),
+			sanity.WithHTTPHost("http", "localhost:3333"))
+		require.NoError(t, err)
+
+		config := c.Config()
+		assert.Equal(t, "localhost:3333", config.APIHost)
+		assert.Equal(t, "localhost:3333", config.QueryHost)
+		assert.False(t, config.UseCDN)
+		assert.False(t, config.HasToken)
+	})
+}
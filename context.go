@@ -0,0 +1,36 @@
+package sanity
+
+import "context"
+
+type tagContextKey struct{}
+
+type contextKey string
+
+// CorrelationIDKey is the context key of a correlation ID, such as one taken from an incoming
+// request, that is sent with every request made with the context. The value must be a string.
+// It is sent in the X-Correlation-ID header, or in the header set with WithCorrelationHeader.
+//
+//	ctx = context.WithValue(ctx, sanity.CorrelationIDKey, id)
+var CorrelationIDKey = contextKey("correlationID")
+
+// DefaultCorrelationHeader is the header in which the correlation ID is sent by default.
+const DefaultCorrelationHeader = "X-Correlation-ID"
+
+// ContextWithTag returns a context that carries a request tag. Requests made with the context
+// are tagged with it, unless a tag is set on the request builder itself, in which case that
+// takes precedence; it overrides the client default set with WithTag. This is how the tag of
+// an operation reaches the requests made by helpers that issue several of them, such as
+// ResolveReferences, so that they are attributed to the operation.
+func ContextWithTag(ctx context.Context, tag string) context.Context {
+	return context.WithValue(ctx, tagContextKey{}, tag)
+}
+
+func tagFromContext(ctx context.Context) string {
+	tag, _ := ctx.Value(tagContextKey{}).(string)
+	return tag
+}
+
+func correlationIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(CorrelationIDKey).(string)
+	return id
+}
@@ -0,0 +1,113 @@
+package sanity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// AcrossOption is an option for MutateAcrossDatasets.
+type AcrossOption func(o *acrossOptions)
+
+type acrossOptions struct {
+	concurrency int
+}
+
+// AcrossConcurrency returns an option that sets the maximum number of datasets mutated at
+// the same time. The default is 4.
+func AcrossConcurrency(n int) AcrossOption {
+	return func(o *acrossOptions) { o.concurrency = n }
+}
+
+// DatasetsError is returned by MutateAcrossDatasets when the mutation fails in one or more
+// datasets.
+type DatasetsError struct {
+	// Errors holds the error of each dataset in which the mutation failed.
+	Errors map[string]error
+}
+
+// Error implements the error interface.
+func (e *DatasetsError) Error() string {
+	datasets := make([]string, 0, len(e.Errors))
+	for dataset := range e.Errors {
+		datasets = append(datasets, dataset)
+	}
+	sort.Strings(datasets)
+
+	msgs := make([]string, len(datasets))
+	for i, dataset := range datasets {
+		msgs[i] = fmt.Sprintf("dataset %q: %s", dataset, e.Errors[dataset])
+	}
+	return "mutation failed in " + strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the errors of the datasets, for use with errors.Is and errors.As.
+func (e *DatasetsError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		errs = append(errs, err)
+	}
+	return errs
+}
+
+// MutateAcrossDatasets applies the same mutation to each of the datasets of the project, such
+// as to keep a shared taxonomy in sync. The mutation is configured once by build, as with
+// Mutate, and then sent to the datasets concurrently, each as its own transaction. The
+// results of the datasets in which the mutation succeeded are returned by dataset name; if
+// it failed in any dataset, the error is of type *DatasetsError.
+func (c *Client) MutateAcrossDatasets(ctx context.Context, datasets []string, build func(*MutationBuilder), opts ...AcrossOption) (map[string]*MutateResult, error) {
+	o := acrossOptions{concurrency: 4}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.concurrency <= 0 {
+		return nil, errors.New("concurrency must be positive")
+	}
+	for _, dataset := range datasets {
+		if dataset == "" {
+			return nil, errors.New("dataset must be set")
+		}
+	}
+
+	template := c.Mutate()
+	build(template)
+
+	var mu sync.Mutex
+	results := make(map[string]*MutateResult, len(datasets))
+	errs := map[string]error{}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, o.concurrency)
+	for _, dataset := range datasets {
+		mb := *template
+		mb.dataset = dataset
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(dataset string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			result, err := mb.Do(ctx)
+
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs[dataset] = err
+			} else {
+				results[dataset] = result
+			}
+		}(dataset)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return results, &DatasetsError{Errors: errs}
+	}
+	return results, nil
+}
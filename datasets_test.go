@@ -0,0 +1,114 @@
+package sanity_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sanity "github.com/sanity-io/client-go"
+	"github.com/sanity-io/client-go/api"
+)
+
+func TestMutateAcrossDatasets(t *testing.T) {
+	build := func(mb *sanity.MutationBuilder) {
+		mb.CreateOrReplace(map[string]string{"_id": "category1", "_type": "category"}).
+			Delete("category2").
+			Tag("sync")
+	}
+
+	t.Run("applies the mutation to each dataset", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			var mu sync.Mutex
+			received := map[string][]*api.MutationItem{}
+			s.mux.Post("/v1/data/mutate/{dataset}", func(w http.ResponseWriter, r *http.Request) {
+				dataset := chi.URLParam(r, "dataset")
+				assert.Equal(t, "sync", r.URL.Query().Get("tag"))
+
+				var req api.MutateRequest
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+				mu.Lock()
+				received[dataset] = req.Mutations
+				mu.Unlock()
+
+				_, err := w.Write(mustJSONBytes(&api.MutateResponse{TransactionID: "tx-" + dataset}))
+				assert.NoError(t, err)
+			})
+
+			datasets := []string{"production", "staging", "development"}
+			results, err := s.client.MutateAcrossDatasets(context.Background(), datasets, build,
+				sanity.AcrossConcurrency(2))
+			require.NoError(t, err)
+
+			want := []*api.MutationItem{
+				{CreateOrReplace: mustJSONMsg(map[string]string{"_id": "category1", "_type": "category"})},
+				{Delete: &api.Delete{ID: "category2"}},
+			}
+			require.Len(t, results, len(datasets))
+			for _, dataset := range datasets {
+				assert.Equal(t, want, received[dataset], dataset)
+				assert.Equal(t, "tx-"+dataset, results[dataset].TransactionID)
+			}
+			_, ok := received["myDataset"]
+			assert.False(t, ok)
+		})
+	})
+
+	t.Run("reports errors per dataset", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Post("/v1/data/mutate/{dataset}", func(w http.ResponseWriter, r *http.Request) {
+				if chi.URLParam(r, "dataset") == "broken" {
+					w.WriteHeader(http.StatusBadRequest)
+					_, _ = w.Write([]byte(`{"error":{"description":"invalid","type":"mutationError"}}`))
+					return
+				}
+				_, err := w.Write(mustJSONBytes(&api.MutateResponse{TransactionID: "tx"}))
+				assert.NoError(t, err)
+			})
+
+			results, err := s.client.MutateAcrossDatasets(context.Background(), []string{"ok", "broken"}, build)
+			require.Error(t, err)
+
+			var dsErr *sanity.DatasetsError
+			require.True(t, errors.As(err, &dsErr))
+			assert.Len(t, dsErr.Errors, 1)
+			assert.Contains(t, dsErr.Errors, "broken")
+
+			var reqErr *sanity.RequestError
+			assert.True(t, errors.As(err, &reqErr))
+
+			assert.Len(t, results, 1)
+			assert.Contains(t, results, "ok")
+		})
+	})
+
+	t.Run("names the target dataset in headers and errors", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Post("/v1/data/mutate/{dataset}", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, chi.URLParam(r, "dataset"), r.Header.Get("X-Sanity-Dataset"))
+				w.WriteHeader(http.StatusBadRequest)
+			})
+
+			_, err := s.client.MutateAcrossDatasets(context.Background(), []string{"other"}, build)
+			require.Error(t, err)
+
+			var reqErr *sanity.RequestError
+			require.True(t, errors.As(err, &reqErr))
+			assert.Equal(t, "other", reqErr.Dataset)
+		}, sanity.WithProjectHeaders(true))
+	})
+
+	t.Run("rejects empty dataset", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			_, err := s.client.MutateAcrossDatasets(context.Background(), []string{"ok", ""}, build)
+			require.Error(t, err)
+		})
+	})
+}
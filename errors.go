@@ -1,10 +1,19 @@
 package sanity
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 )
 
+// ErrDocumentNotFound is returned when a requested document does not exist.
+var ErrDocumentNotFound = errors.New("document not found")
+
+// ErrPreconditionFailed is returned when a condition set with MutationBuilder.If does not
+// hold, in which case the mutation is not sent.
+var ErrPreconditionFailed = errors.New("precondition failed")
+
 // RequestError is returned for API requests that fail with a non-successful HTTP status code.
 type RequestError struct {
 	// Request is the attempted HTTP request that failed.
@@ -15,6 +24,12 @@ type RequestError struct {
 
 	// Body is the body of the response.
 	Body []byte
+
+	// ProjectID is the ID of the project the client was configured with.
+	ProjectID string
+
+	// Dataset is the dataset the client was configured with.
+	Dataset string
 }
 
 // Error implements the error interface.
@@ -27,8 +42,126 @@ func (e *RequestError) Error() string {
 
 	msg := fmt.Sprintf("HTTP request [%s %s] failed with status %d",
 		e.Request.Method, e.Request.URL.String(), e.Response.StatusCode)
+	if e.ProjectID != "" || e.Dataset != "" {
+		msg += fmt.Sprintf(" (project %q, dataset %q)", e.ProjectID, e.Dataset)
+	}
 	if body != "" {
 		msg += ": " + body
 	}
 	return msg
 }
+
+// StatusCode returns the HTTP status code of the response.
+func (e *RequestError) StatusCode() int {
+	if e.Response == nil {
+		return 0
+	}
+	return e.Response.StatusCode
+}
+
+// StatusCode returns the HTTP status code of the *RequestError wrapped by err, if any. The
+// boolean is false if err does not wrap a *RequestError.
+func StatusCode(err error) (int, bool) {
+	var reqErr *RequestError
+	if !errors.As(err, &reqErr) {
+		return 0, false
+	}
+	return reqErr.StatusCode(), true
+}
+
+// Description returns the error description reported by the API. The boolean is false if
+// the body could not be parsed as a Sanity error response.
+func (e *RequestError) Description() (string, bool) {
+	details, ok := e.details()
+	if !ok || details.Description == "" {
+		return "", false
+	}
+	return details.Description, true
+}
+
+// Type returns the error type reported by the API, such as "mutationError" or
+// "queryParseError". The boolean is false if the body could not be parsed as a
+// Sanity error response.
+func (e *RequestError) Type() (string, bool) {
+	details, ok := e.details()
+	if !ok || details.Type == "" {
+		return "", false
+	}
+	return details.Type, true
+}
+
+// QuotaError is returned when a request is rejected because the project has exhausted its
+// quota, as opposed to being temporarily rate limited: with status 402, or with status 429
+// and an error of type quotaExceededError. Unlike rate-limited requests, these are not
+// retried, since retrying cannot succeed until the quota is raised or reset. It wraps the
+// *RequestError of the response.
+type QuotaError struct {
+	*RequestError
+}
+
+// Error implements the error interface.
+func (e *QuotaError) Error() string {
+	return "quota exceeded: " + e.RequestError.Error()
+}
+
+// Unwrap returns the underlying *RequestError.
+func (e *QuotaError) Unwrap() error {
+	return e.RequestError
+}
+
+// QueryLengthError is returned for queries whose GET request URL would exceed the maximum
+// length, when the client is configured with ExceedError or ExceedTruncate.
+type QueryLengthError struct {
+	// Length is the length of the GET request URL.
+	Length int
+
+	// Max is the maximum length.
+	Max int
+
+	// Query is the start of the query, with ExceedTruncate. It is empty with ExceedError.
+	Query string
+}
+
+// Error implements the error interface.
+func (e *QueryLengthError) Error() string {
+	msg := fmt.Sprintf("query URL length %d exceeds maximum of %d", e.Length, e.Max)
+	if e.Query != "" {
+		msg += fmt.Sprintf(": %q", e.Query)
+	}
+	return msg
+}
+
+// quotaErrorType is the error type the API reports when a project has exhausted its quota.
+const quotaErrorType = "quotaExceededError"
+
+// isQuotaExceeded reports whether an error response is due to quota exhaustion rather than a
+// transient rate limit: either a 402 response, or a 429 response whose error object has the
+// quota error type. Other 429 responses are rate limits, including those with a body of the
+// form {"error":"...","message":"..."}, which has no error type.
+func (e *RequestError) isQuotaExceeded() bool {
+	switch e.StatusCode() {
+	case http.StatusPaymentRequired:
+		return true
+	case http.StatusTooManyRequests:
+		typ, _ := e.Type()
+		return typ == quotaErrorType
+	}
+	return false
+}
+
+// errorDetails is the error object of a Sanity error response body.
+type errorDetails struct {
+	Description string             `json:"description"`
+	Type        string             `json:"type"`
+	Items       []*json.RawMessage `json:"items"`
+}
+
+func (e *RequestError) details() (*errorDetails, bool) {
+	var body struct {
+		Error *errorDetails `json:"error"`
+	}
+	if err := json.Unmarshal(e.Body, &body); err != nil || body.Error == nil {
+		return nil, false
+	}
+	return body.Error, true
+}
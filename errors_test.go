@@ -0,0 +1,192 @@
+package sanity_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/jpillora/backoff"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sanity "github.com/sanity-io/client-go"
+)
+
+func TestRequestError_details(t *testing.T) {
+	t.Run("parseable body", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+				_, err := w.Write([]byte(`{"error":{"description":"expected ']'","type":"queryParseError"}}`))
+				assert.NoError(t, err)
+			})
+
+			_, err := s.client.Query("*[").Do(context.Background())
+			require.Error(t, err)
+
+			var reqErr *sanity.RequestError
+			require.True(t, errors.As(err, &reqErr))
+
+			assert.Equal(t, http.StatusBadRequest, reqErr.StatusCode())
+
+			desc, ok := reqErr.Description()
+			assert.True(t, ok)
+			assert.Equal(t, "expected ']'", desc)
+
+			typ, ok := reqErr.Type()
+			assert.True(t, ok)
+			assert.Equal(t, "queryParseError", typ)
+
+			assert.Contains(t, reqErr.Error(), `"type":"queryParseError"`)
+		})
+	})
+
+	t.Run("non-JSON body", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadGateway)
+				_, err := w.Write([]byte("bad gateway"))
+				assert.NoError(t, err)
+			})
+
+			_, err := s.client.Query("*").Do(context.Background())
+			require.Error(t, err)
+
+			var reqErr *sanity.RequestError
+			require.True(t, errors.As(err, &reqErr))
+
+			assert.Equal(t, http.StatusBadGateway, reqErr.StatusCode())
+
+			_, ok := reqErr.Description()
+			assert.False(t, ok)
+
+			_, ok = reqErr.Type()
+			assert.False(t, ok)
+
+			assert.Contains(t, reqErr.Error(), "bad gateway")
+		})
+	})
+}
+
+func TestRequestError_projectAndDataset(t *testing.T) {
+	withSuite(t, func(s *Suite) {
+		s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		})
+
+		_, err := s.client.Query("*").Do(context.Background())
+		require.Error(t, err)
+
+		var reqErr *sanity.RequestError
+		require.True(t, errors.As(err, &reqErr))
+		assert.Equal(t, "myProject", reqErr.ProjectID)
+		assert.Equal(t, "myDataset", reqErr.Dataset)
+		assert.Contains(t, reqErr.Error(), `(project "myProject", dataset "myDataset")`)
+	})
+}
+
+func TestTooManyRequests(t *testing.T) {
+	for _, tc := range []struct {
+		desc      string
+		status    int
+		body      string
+		wantQuota bool
+		wantCalls int
+	}{
+		{
+			desc:      "rate limit is retried",
+			status:    http.StatusTooManyRequests,
+			body:      `{"statusCode":429,"error":"Too Many Requests","message":"API rate limit exceeded"}`,
+			wantCalls: 3,
+		},
+		{
+			desc:      "rate limit mentioning quota is retried",
+			status:    http.StatusTooManyRequests,
+			body:      `{"statusCode":429,"error":"Too Many Requests","message":"Request quota per second exceeded"}`,
+			wantCalls: 3,
+		},
+		{
+			desc:      "rate limit error object is retried",
+			status:    http.StatusTooManyRequests,
+			body:      `{"error":{"type":"rateLimitError","description":"Too many requests, within your quota"}}`,
+			wantCalls: 3,
+		},
+		{
+			desc:      "quota error object is not retried",
+			status:    http.StatusTooManyRequests,
+			body:      `{"error":{"type":"quotaExceededError","description":"Project has exceeded its API request quota"}}`,
+			wantQuota: true,
+			wantCalls: 1,
+		},
+		{
+			desc:      "payment required is a quota error",
+			status:    http.StatusPaymentRequired,
+			body:      `{"statusCode":402,"error":"Payment Required","message":"Monthly API quota exceeded"}`,
+			wantQuota: true,
+			wantCalls: 1,
+		},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			withSuite(t, func(s *Suite) {
+				calls := 0
+				s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+					calls++
+					w.WriteHeader(tc.status)
+					_, err := w.Write([]byte(tc.body))
+					assert.NoError(t, err)
+				})
+
+				_, err := s.client.Mutate().Delete("doc1").Do(context.Background())
+				require.Error(t, err)
+				assert.Equal(t, tc.wantCalls, calls)
+
+				var quotaErr *sanity.QuotaError
+				assert.Equal(t, tc.wantQuota, errors.As(err, &quotaErr))
+
+				var reqErr *sanity.RequestError
+				require.True(t, errors.As(err, &reqErr))
+				assert.Equal(t, tc.status, reqErr.StatusCode())
+			},
+				sanity.WithRetryMax(2),
+				sanity.WithBackoff(backoff.Backoff{Min: time.Millisecond, Max: time.Millisecond}),
+			)
+		})
+	}
+}
+
+func TestStatusCode(t *testing.T) {
+	withSuite(t, func(s *Suite) {
+		s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusConflict)
+		})
+
+		_, err := s.client.Mutate().Delete("doc1").Do(context.Background())
+		require.Error(t, err)
+
+		var reqErr *sanity.RequestError
+		require.True(t, errors.As(err, &reqErr))
+
+		for _, tc := range []struct {
+			desc string
+			err  error
+		}{
+			{"unwrapped", reqErr},
+			{"wrapped", err},
+			{"wrapped twice", fmt.Errorf("saving: %w", err)},
+		} {
+			code, ok := sanity.StatusCode(tc.err)
+			assert.True(t, ok, tc.desc)
+			assert.Equal(t, http.StatusConflict, code, tc.desc)
+		}
+
+		for _, err := range []error{nil, errors.New("other")} {
+			code, ok := sanity.StatusCode(err)
+			assert.False(t, ok)
+			assert.Equal(t, 0, code)
+		}
+	})
+}
@@ -0,0 +1,43 @@
+package sanity
+
+import (
+	"context"
+	"fmt"
+)
+
+// existsChunkSize is the maximum number of IDs checked with a single query.
+const existsChunkSize = 500
+
+// ExistsMany reports which of the documents with the given IDs exist, mapping every
+// requested ID to whether it exists. The IDs are checked with *[_id in $ids]._id queries of
+// up to 500 IDs each, rather than one request per document.
+//
+// IDs are matched as stored: a draft is a separate document with an ID prefixed with
+// "drafts.", so checking "post1" does not report its draft and vice versa. Drafts are only
+// visible to a client with a token that can read them.
+func (c *Client) ExistsMany(ctx context.Context, ids []string) (map[string]bool, error) {
+	exists := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		exists[id] = false
+	}
+
+	for start := 0; start < len(ids); start += existsChunkSize {
+		chunk := ids[start:min(start+existsChunkSize, len(ids))]
+
+		result, err := c.storedQuery("*[_id in $ids]._id").Param("ids", chunk).Do(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		var found []string
+		if err := result.Unmarshal(&found); err != nil {
+			return nil, fmt.Errorf("decoding document IDs: %w", err)
+		}
+		for _, id := range found {
+			if _, ok := exists[id]; ok {
+				exists[id] = true
+			}
+		}
+	}
+	return exists, nil
+}
@@ -0,0 +1,121 @@
+package sanity_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sanity "github.com/sanity-io/client-go"
+	"github.com/sanity-io/client-go/api"
+)
+
+// serveExisting serves *[_id in $ids]._id queries for a dataset holding the given documents,
+// recording the number of IDs in each query.
+func serveExisting(t *testing.T, s *Suite, existing map[string]bool, chunks *[]int) {
+	s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "*[_id in $ids]._id", r.URL.Query().Get("query"))
+		serveExistingIDs(t, w, r.URL.Query().Get("$ids"), existing, chunks)
+	})
+	s.mux.Post("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+		var req api.QueryRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "*[_id in $ids]._id", req.Query)
+		serveExistingIDs(t, w, string(*req.Params["ids"]), existing, chunks)
+	})
+}
+
+func serveExistingIDs(t *testing.T, w http.ResponseWriter, param string, existing map[string]bool, chunks *[]int) {
+	var ids []string
+	require.NoError(t, json.Unmarshal([]byte(param), &ids))
+	*chunks = append(*chunks, len(ids))
+
+	found := []string{}
+	for _, id := range ids {
+		if existing[id] {
+			found = append(found, id)
+		}
+	}
+	_, err := w.Write(mustJSONBytes(&api.QueryResponse{Result: mustJSONMsg(found)}))
+	assert.NoError(t, err)
+}
+
+func TestExistsMany(t *testing.T) {
+	t.Run("mix of existing and missing", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			var chunks []int
+			serveExisting(t, s, map[string]bool{"a": true, "drafts.b": true}, &chunks)
+
+			exists, err := s.client.ExistsMany(context.Background(), []string{"a", "b", "drafts.b", "c"})
+			require.NoError(t, err)
+			assert.Equal(t, map[string]bool{"a": true, "b": false, "drafts.b": true, "c": false}, exists)
+			assert.Equal(t, []int{4}, chunks)
+		})
+	})
+
+	t.Run("chunks large ID lists", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			existing := map[string]bool{}
+			ids := make([]string, 1200)
+			for i := range ids {
+				ids[i] = fmt.Sprintf("doc%d", i)
+				existing[ids[i]] = i%3 == 0
+			}
+
+			var chunks []int
+			serveExisting(t, s, existing, &chunks)
+
+			exists, err := s.client.ExistsMany(context.Background(), ids)
+			require.NoError(t, err)
+			assert.Equal(t, existing, exists)
+			assert.Equal(t, []int{500, 500, 200}, chunks)
+		})
+	})
+
+	t.Run("raw perspective", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "raw", r.URL.Query().Get("perspective"))
+				_, err := w.Write(mustJSONBytes(&api.QueryResponse{Result: mustJSONMsg([]string{"a"})}))
+				assert.NoError(t, err)
+			})
+
+			exists, err := s.client.ExistsMany(context.Background(), []string{"a"})
+			require.NoError(t, err)
+			assert.Equal(t, map[string]bool{"a": true}, exists)
+		}, sanity.WithPerspective("previewDrafts"))
+	})
+
+	t.Run("bypasses CDN", func(t *testing.T) {
+		mux := chi.NewRouter()
+		var chunks []int
+		serveExisting(t, &Suite{mux: mux}, map[string]bool{"a": true}, &chunks)
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		transport := &redirectTransport{server: server}
+		c, err := sanity.VersionV1.NewClient("myProject", "myDataset",
+			sanity.WithCDN(true),
+			sanity.WithHTTPClient(&http.Client{Transport: transport}))
+		require.NoError(t, err)
+
+		exists, err := c.ExistsMany(context.Background(), []string{"a", "b"})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]bool{"a": true, "b": false}, exists)
+		assert.Equal(t, []string{"myProject.api.sanity.io"}, transport.hosts)
+	})
+
+	t.Run("no IDs", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			exists, err := s.client.ExistsMany(context.Background(), nil)
+			require.NoError(t, err)
+			assert.Empty(t, exists)
+		})
+	})
+}
@@ -0,0 +1,126 @@
+package sanity
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/sanity-io/client-go/api"
+	"github.com/sanity-io/client-go/internal/requests"
+)
+
+// Export returns a new builder for exporting the entire dataset.
+func (c *Client) Export() *ExportBuilder {
+	return &ExportBuilder{c: c}
+}
+
+// ExportBuilder is a builder for the export API, which streams all documents of the dataset
+// as newline-delimited JSON (NDJSON), one document per line.
+type ExportBuilder struct {
+	c     *Client
+	types []string
+	tag   string
+}
+
+// Types limits the export to documents of the given types.
+func (b *ExportBuilder) Types(types ...string) *ExportBuilder {
+	b.types = append(b.types, types...)
+	return b
+}
+
+// Tag sets the request tag, overriding the client default set with WithTag.
+func (b *ExportBuilder) Tag(tag string) *ExportBuilder {
+	b.tag = tag
+	return b
+}
+
+// Do starts the export and returns the NDJSON response body, which the caller must close.
+// On API request failure, this will return an error of type *RequestError.
+func (b *ExportBuilder) Do(ctx context.Context) (io.ReadCloser, error) {
+	ctx, cancel := b.c.withTimeout(ctx)
+
+	resp, err := b.c.send(ctx, b.buildRequest())
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}, nil
+}
+
+// DoStream performs the export and calls fn with each document as it is decoded from the
+// response, so that only one document is held in memory at a time. If fn returns an error,
+// the export is stopped and the error is returned.
+// On API request failure, this will return an error of type *RequestError.
+func (b *ExportBuilder) DoStream(ctx context.Context, fn func(api.Document) error) error {
+	body, err := b.Do(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = body.Close()
+	}()
+
+	dec := json.NewDecoder(body)
+	if b.c.useNumber {
+		dec.UseNumber()
+	}
+	for {
+		var doc api.Document
+		if err := dec.Decode(&doc); errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("decoding document: %w", err)
+		}
+
+		if err := fn(doc); err != nil {
+			return err
+		}
+	}
+}
+
+func (b *ExportBuilder) buildRequest() *requests.Request {
+	req := b.c.newAPIRequest().
+		AppendPath("data/export", b.c.dataset).
+		Tag(b.tag, b.c.tag)
+	if len(b.types) > 0 {
+		req.Param("types", strings.Join(b.types, ","))
+	}
+	return req
+}
+
+// cancelOnClose releases a context when the body read under it is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (r *cancelOnClose) Close() error {
+	defer r.cancel()
+	return r.ReadCloser.Close()
+}
+
+// ExportQuery performs the query and writes each element of the result, which must be an
+// array, to w as newline-delimited JSON (NDJSON), in the same format as Export. This allows
+// exporting only the documents matched by a query, such as for a partial backup. The result
+// is streamed, so that only one element is held in memory at a time.
+// On API request failure, this will return an error of type *RequestError.
+func (c *Client) ExportQuery(ctx context.Context, query string, w io.Writer, params ...QueryParam) error {
+	var line bytes.Buffer
+	return c.Query(query).withParams(params).Stream(ctx).ForEach(func(raw json.RawMessage) error {
+		line.Reset()
+		if err := json.Compact(&line, raw); err != nil {
+			return fmt.Errorf("encoding document: %w", err)
+		}
+		line.WriteByte('\n')
+		if _, err := w.Write(line.Bytes()); err != nil {
+			return fmt.Errorf("writing document: %w", err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,178 @@
+package sanity_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sanity "github.com/sanity-io/client-go"
+	"github.com/sanity-io/client-go/api"
+)
+
+func TestExport(t *testing.T) {
+	body := "{\"_id\":\"a\",\"_type\":\"post\"}\n{\"_id\":\"b\",\"_type\":\"author\"}\n{\"_id\":\"c\",\"_type\":\"post\"}\n"
+
+	t.Run("returns NDJSON body", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/export/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				assert.Empty(t, r.URL.Query().Get("types"))
+				_, err := w.Write([]byte(body))
+				assert.NoError(t, err)
+			})
+
+			rc, err := s.client.Export().Do(context.Background())
+			require.NoError(t, err)
+			defer rc.Close()
+
+			b, err := ioutil.ReadAll(rc)
+			require.NoError(t, err)
+			assert.Equal(t, body, string(b))
+		})
+	})
+
+	t.Run("streams documents with types filter", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/export/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "post,author", r.URL.Query().Get("types"))
+				_, err := w.Write([]byte(body))
+				assert.NoError(t, err)
+			})
+
+			var ids []string
+			err := s.client.Export().Types("post", "author").DoStream(context.Background(), func(doc api.Document) error {
+				ids = append(ids, doc["_id"].(string))
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, []string{"a", "b", "c"}, ids)
+		})
+	})
+
+	t.Run("returns request error", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/export/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+			})
+
+			_, err := s.client.Export().Do(context.Background())
+			require.Error(t, err)
+
+			var reqErr *sanity.RequestError
+			require.True(t, errors.As(err, &reqErr))
+			assert.Equal(t, http.StatusForbidden, reqErr.StatusCode())
+		})
+	})
+}
+
+func TestExport_jsonNumbers(t *testing.T) {
+	for _, tc := range []struct {
+		desc   string
+		enable bool
+		want   interface{}
+	}{
+		{desc: "enabled", enable: true, want: json.Number("9007199254740993")},
+		{desc: "disabled", enable: false, want: float64(9007199254740992)},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			withSuite(t, func(s *Suite) {
+				s.mux.Get("/v1/data/export/myDataset", func(w http.ResponseWriter, r *http.Request) {
+					_, err := w.Write([]byte(`{"_id":"a","count":9007199254740993}` + "\n"))
+					assert.NoError(t, err)
+				})
+
+				var counts []interface{}
+				err := s.client.Export().DoStream(context.Background(), func(doc api.Document) error {
+					counts = append(counts, doc["count"])
+					return nil
+				})
+				require.NoError(t, err)
+				assert.Equal(t, []interface{}{tc.want}, counts)
+			}, sanity.WithJSONNumbers(tc.enable))
+		})
+	}
+}
+
+func TestExportQuery(t *testing.T) {
+	t.Run("writes result as NDJSON", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, `*[_type == $type]`, r.URL.Query().Get("query"))
+				assert.Equal(t, `"post"`, r.URL.Query().Get("$type"))
+				_, err := w.Write([]byte(`{"ms":1,"result":[
+					{"_id": "a", "_type": "post", "tags": ["x", "y"]},
+					{"_id": "c", "_type": "post"}
+				]}`))
+				assert.NoError(t, err)
+			})
+
+			var buf bytes.Buffer
+			err := s.client.ExportQuery(context.Background(), `*[_type == $type]`, &buf, sanity.Param("type", "post"))
+			require.NoError(t, err)
+			assert.Equal(t,
+				"{\"_id\":\"a\",\"_type\":\"post\",\"tags\":[\"x\",\"y\"]}\n{\"_id\":\"c\",\"_type\":\"post\"}\n",
+				buf.String())
+		})
+	})
+
+	t.Run("empty result", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				_, err := w.Write([]byte(`{"ms":1,"result":[]}`))
+				assert.NoError(t, err)
+			})
+
+			var buf bytes.Buffer
+			require.NoError(t, s.client.ExportQuery(context.Background(), `*[false]`, &buf))
+			assert.Empty(t, buf.String())
+		})
+	})
+
+	t.Run("large result", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			serveStream(t, s, 5000, 100, 500, 0)
+
+			var buf bytes.Buffer
+			require.NoError(t, s.client.ExportQuery(context.Background(), `*`, &buf))
+
+			lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+			require.Len(t, lines, 5000)
+			for i, line := range lines {
+				var doc api.Document
+				require.NoError(t, json.Unmarshal([]byte(line), &doc))
+				assert.Equal(t, fmt.Sprintf("doc%d", i), doc["_id"])
+			}
+		})
+	})
+
+	t.Run("write error", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				_, err := w.Write([]byte(`{"ms":1,"result":[{"_id":"a"}]}`))
+				assert.NoError(t, err)
+			})
+
+			writeErr := errors.New("disk full")
+			err := s.client.ExportQuery(context.Background(), `*`, failingWriter{writeErr})
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, writeErr))
+		})
+	})
+}
+
+type failingWriter struct {
+	err error
+}
+
+func (w failingWriter) Write([]byte) (int, error) {
+	return 0, w.err
+}
@@ -0,0 +1,146 @@
+package sanity
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FragmentKind is the kind of a query fragment.
+type FragmentKind int
+
+const (
+	// FragmentFilter is a fragment holding a filter expression, such as
+	// `_type == "author"`.
+	FragmentFilter FragmentKind = iota
+
+	// FragmentProjection is a fragment holding projection fields, such as `name, bio`.
+	FragmentProjection
+)
+
+// Fragment is a reusable snippet of GROQ that can be included into queries with
+// QueryBuilder.Include.
+type Fragment struct {
+	kind FragmentKind
+	body string
+}
+
+// FilterFragment returns a fragment holding a filter expression. Filters included into a
+// query are combined with && and appended to the query inside a single filter.
+func FilterFragment(expr string) Fragment {
+	return Fragment{kind: FragmentFilter, body: strings.TrimSpace(expr)}
+}
+
+// ProjectionFragment returns a fragment holding projection fields. The surrounding braces
+// are optional. Projections included into a query are merged and appended to the query as a
+// single projection.
+func ProjectionFragment(fields string) Fragment {
+	fields = strings.TrimSpace(fields)
+	if strings.HasPrefix(fields, "{") && strings.HasSuffix(fields, "}") {
+		fields = strings.TrimSpace(fields[1 : len(fields)-1])
+	}
+	return Fragment{kind: FragmentProjection, body: fields}
+}
+
+// Kind returns the kind of the fragment.
+func (f Fragment) Kind() FragmentKind {
+	return f.kind
+}
+
+// String returns the GROQ of the fragment.
+func (f Fragment) String() string {
+	return f.body
+}
+
+// Validate checks that the fragment is non-empty and that its brackets and string literals
+// are balanced, so that it cannot break the query it is included into.
+func (f Fragment) Validate() error {
+	if f.body == "" {
+		return errors.New("fragment is empty")
+	}
+
+	var stack []rune
+	var quote rune
+	escaped := false
+	for _, r := range f.body {
+		if quote != 0 {
+			switch {
+			case escaped:
+				escaped = false
+			case r == '\\':
+				escaped = true
+			case r == quote:
+				quote = 0
+			}
+			continue
+		}
+
+		switch r {
+		case '"', '\'':
+			quote = r
+		case '(', '[', '{':
+			stack = append(stack, r)
+		case ')', ']', '}':
+			open := map[rune]rune{')': '(', ']': '[', '}': '{'}[r]
+			if len(stack) == 0 || stack[len(stack)-1] != open {
+				return fmt.Errorf("fragment %q has unbalanced %q", f.body, r)
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	if quote != 0 {
+		return fmt.Errorf("fragment %q has an unterminated string", f.body)
+	}
+	if len(stack) > 0 {
+		return fmt.Errorf("fragment %q has unbalanced %q", f.body, stack[len(stack)-1])
+	}
+	return nil
+}
+
+// Include adds a fragment to the query. Filters are appended first, combined into a single
+// filter, followed by all projections merged into a single projection. For example,
+// including FilterFragment(`defined(slug)`) and ProjectionFragment(`title`) into the query
+// `*[_type == "post"]` produces `*[_type == "post"][(defined(slug))]{title}`.
+//
+// Fragments can only be included into a query that does not already end in a projection,
+// since they would otherwise filter and project its result rather than the documents.
+func (qb *QueryBuilder) Include(frag Fragment) *QueryBuilder {
+	if err := frag.Validate(); err != nil {
+		qb.setErr(err)
+		return qb
+	}
+	if strings.HasSuffix(strings.TrimSpace(qb.query), "}") {
+		qb.setErr(fmt.Errorf("cannot include fragment %q into a query ending in a projection", frag.body))
+		return qb
+	}
+
+	qb.fragments = append(qb.fragments, frag)
+	return qb
+}
+
+// composedQuery returns the query with all included fragments appended.
+func (qb *QueryBuilder) composedQuery() string {
+	if len(qb.fragments) == 0 {
+		return qb.query
+	}
+
+	var filters, projections []string
+	for _, frag := range qb.fragments {
+		switch frag.kind {
+		case FragmentFilter:
+			filters = append(filters, "("+frag.body+")")
+		case FragmentProjection:
+			projections = append(projections, frag.body)
+		}
+	}
+
+	query := qb.query
+	if len(filters) > 0 {
+		query += "[" + strings.Join(filters, " && ") + "]"
+	}
+	if len(projections) > 0 {
+		query += "{" + strings.Join(projections, ", ") + "}"
+	}
+	return query
+}
@@ -0,0 +1,113 @@
+package sanity_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sanity "github.com/sanity-io/client-go"
+	"github.com/sanity-io/client-go/api"
+)
+
+func TestQuery_Include(t *testing.T) {
+	authorFields := sanity.ProjectionFragment(`{ name, "image": image.asset->url }`)
+	postFields := sanity.ProjectionFragment(`title, "author": author->{name}`)
+	published := sanity.FilterFragment(`!(_id in path("drafts.**"))`)
+	hasSlug := sanity.FilterFragment(`defined(slug.current)`)
+
+	for _, tc := range []struct {
+		desc      string
+		fragments []sanity.Fragment
+		expect    string
+	}{
+		{
+			"no fragments",
+			nil,
+			`*[_type == "post"]`,
+		},
+		{
+			"single projection",
+			[]sanity.Fragment{authorFields},
+			`*[_type == "post"]{name, "image": image.asset->url}`,
+		},
+		{
+			"merged projections",
+			[]sanity.Fragment{postFields, authorFields},
+			`*[_type == "post"]{title, "author": author->{name}, name, "image": image.asset->url}`,
+		},
+		{
+			"filters and projections",
+			[]sanity.Fragment{postFields, published, hasSlug},
+			`*[_type == "post"][(!(_id in path("drafts.**"))) && (defined(slug.current))]{title, "author": author->{name}}`,
+		},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			withSuite(t, func(s *Suite) {
+				s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+					assert.Equal(t, tc.expect, r.URL.Query().Get("query"))
+
+					w.WriteHeader(http.StatusOK)
+					_, err := w.Write(mustJSONBytes(&api.QueryResponse{}))
+					assert.NoError(t, err)
+				})
+
+				qb := s.client.Query(`*[_type == "post"]`)
+				for _, frag := range tc.fragments {
+					qb.Include(frag)
+				}
+
+				_, err := qb.Do(context.Background())
+				require.NoError(t, err)
+			})
+		})
+	}
+}
+
+func TestFragment_Validate(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		frag    sanity.Fragment
+		wantErr bool
+	}{
+		{"valid filter", sanity.FilterFragment(`count(tags[@ == "x"]) > 0`), false},
+		{"brackets inside strings", sanity.FilterFragment(`title == "a ] b"`), false},
+		{"escaped quote", sanity.FilterFragment(`title == "a \" ]"`), false},
+		{"empty", sanity.FilterFragment(" "), true},
+		{"empty projection", sanity.ProjectionFragment("{}"), true},
+		{"unbalanced bracket", sanity.FilterFragment(`a == 1]`), true},
+		{"unclosed paren", sanity.FilterFragment(`defined(a`), true},
+		{"mismatched", sanity.ProjectionFragment(`"a": b[}`), true},
+		{"unterminated string", sanity.FilterFragment(`title == "a`), true},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			err := tc.frag.Validate()
+			if tc.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+
+	t.Run("invalid fragment fails query", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			_, err := s.client.Query("*").Include(sanity.FilterFragment("a]")).Do(context.Background())
+			require.Error(t, err)
+		})
+	})
+
+	t.Run("rejects query ending in a projection", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			_, err := s.client.Query(`*[_type == "post"]{title} `).
+				Include(sanity.FilterFragment("defined(slug)")).
+				Do(context.Background())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "ending in a projection")
+		})
+	})
+}
@@ -2,9 +2,14 @@ package sanity
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"strings"
 
 	"github.com/sanity-io/client-go/api"
+	"github.com/sanity-io/client-go/internal/requests"
 )
 
 // GetDocuments returns a new GetDocuments builder.
@@ -12,28 +17,77 @@ func (c *Client) GetDocuments(docIDs ...string) *GetDocumentsBuilder {
 	return &GetDocumentsBuilder{c: c, docIDs: docIDs}
 }
 
+// GetRevision returns the current revision ID (_rev) of the document, without fetching the
+// rest of it. This is useful for optimistic concurrency with PatchBuilder.IfRevisionID. If
+// the document does not exist, ErrDocumentNotFound is returned.
+func (c *Client) GetRevision(ctx context.Context, id string) (string, error) {
+	result, err := c.storedQuery("*[_id == $id][0]._rev").Param("id", id).Do(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	var rev *string
+	if err := result.Unmarshal(&rev); err != nil {
+		return "", fmt.Errorf("decoding revision: %w", err)
+	}
+	if rev == nil {
+		return "", fmt.Errorf("%w: %q", ErrDocumentNotFound, id)
+	}
+	return *rev, nil
+}
+
 // QueryBuilder is a builder for GET documents API.
 type GetDocumentsBuilder struct {
-	c      *Client
-	docIDs []string
-	tag    string
+	c            *Client
+	docIDs       []string
+	tag          string
+	maxChunkSize int
 }
 
+// Tag sets the request tag, overriding the client default set with WithTag.
 func (b *GetDocumentsBuilder) Tag(tag string) *GetDocumentsBuilder {
 	b.tag = tag
 	return b
 }
 
-// Do performs the query.
+// initialChunkLength is the number of documents in the first request of a chunked fetch,
+// before the size of the documents is known.
+const initialChunkLength = 10
+
+// ChunkResponseSize makes Do and Into fetch the documents in several requests, each of which
+// is meant to return at most about maxBytes of documents, so that fetching many large
+// documents does not require holding a single huge response in memory. The first request
+// is for a few documents; the number of documents in each following request is adjusted to
+// the average size of the documents received so far, growing at most twofold per request.
+// The limit is an estimate, so a response may still exceed it if document sizes vary.
+func (b *GetDocumentsBuilder) ChunkResponseSize(maxBytes int) *GetDocumentsBuilder {
+	b.maxChunkSize = maxBytes
+	return b
+}
+
+// Do performs the query. If there are too many IDs to fit in the request URL, the documents
+// are fetched with a query sent as a POST request instead.
 // On API request failure, this will return an error of type *RequestError.
 func (b *GetDocumentsBuilder) Do(ctx context.Context) (*api.GetDocumentsResponse, error) {
 	if len(b.docIDs) == 0 {
 		return &api.GetDocumentsResponse{}, nil
 	}
 
-	req := b.c.newAPIRequest().
-		AppendPath("data/doc", b.c.dataset, strings.Join(b.docIDs, ",")).
-		Tag(b.tag, b.c.tag)
+	req := b.buildRequest()
+	if b.maxChunkSize > 0 || b.exceedsURLLength(req) {
+		raws, err := b.splitDocuments(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		resp := api.GetDocumentsResponse{Documents: make([]api.Document, len(raws))}
+		for i, raw := range raws {
+			if err := unmarshalJSON(raw, &resp.Documents[i], b.c.useNumber); err != nil {
+				return nil, fmt.Errorf("decoding document: %w", err)
+			}
+		}
+		return &resp, nil
+	}
 
 	var resp api.GetDocumentsResponse
 	if _, err := b.c.do(ctx, req, &resp); err != nil {
@@ -42,3 +96,234 @@ func (b *GetDocumentsBuilder) Do(ctx context.Context) (*api.GetDocumentsResponse
 
 	return &resp, nil
 }
+
+// splitDocuments fetches the documents without a single documents API request, either in
+// chunks if ChunkResponseSize is set, or with a query.
+func (b *GetDocumentsBuilder) splitDocuments(ctx context.Context) ([]json.RawMessage, error) {
+	if b.maxChunkSize > 0 {
+		return b.chunkedDocuments(ctx)
+	}
+	return b.queryDocuments(ctx)
+}
+
+// chunkedDocuments fetches the documents in chunks whose length is adjusted to keep each
+// response within maxChunkSize.
+func (b *GetDocumentsBuilder) chunkedDocuments(ctx context.Context) ([]json.RawMessage, error) {
+	docs := make([]json.RawMessage, 0, len(b.docIDs))
+	err := b.eachChunk(ctx, func(chunkDocs []json.RawMessage) error {
+		docs = append(docs, chunkDocs...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// eachChunk fetches the documents in chunks whose length is adjusted to keep each response
+// within maxChunkSize, and calls fn with the documents of each chunk.
+func (b *GetDocumentsBuilder) eachChunk(ctx context.Context, fn func([]json.RawMessage) error) error {
+	length := initialChunkLength
+	for start := 0; start < len(b.docIDs); {
+		end := start + length
+		if end > len(b.docIDs) {
+			end = len(b.docIDs)
+		}
+
+		chunk := &GetDocumentsBuilder{c: b.c, docIDs: b.docIDs[start:end], tag: b.tag}
+		raw, err := chunk.rawDocuments(ctx)
+		if err != nil {
+			return err
+		}
+
+		var chunkDocs []json.RawMessage
+		if err := json.Unmarshal(raw, &chunkDocs); err != nil {
+			return fmt.Errorf("decoding documents: %w", err)
+		}
+		if err := fn(chunkDocs); err != nil {
+			return err
+		}
+		start = end
+
+		if len(chunkDocs) > 0 {
+			docSize := len(raw)/len(chunkDocs) + 1
+			length = min(2*length, max(1, b.maxChunkSize/docSize))
+		}
+	}
+	return nil
+}
+
+// Into fetches the documents and unmarshals them directly into dest, which must be a pointer
+// to a slice, such as *[]Post. Documents are in the order returned by the API. If no
+// documents are found, the slice is left empty.
+// On API request failure, this will return an error of type *RequestError.
+func (b *GetDocumentsBuilder) Into(ctx context.Context, dest interface{}) error {
+	docs, err := b.rawDocuments(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := unmarshalJSON(docs, dest, b.c.useNumber); err != nil {
+		return fmt.Errorf("decoding documents: %w", err)
+	}
+	return nil
+}
+
+// DoStream fetches the documents and calls fn with each document as it is decoded from the
+// response, so that only one document is held in memory at a time. If fn returns an error,
+// the stream is stopped and the error is returned.
+//
+// If ChunkResponseSize is set, the documents are fetched in chunks as with Do, and only the
+// documents of one chunk are held in memory at a time. Otherwise, if there are too many IDs
+// to fit in the request URL, the documents are fetched with a query sent as a POST request,
+// and all of them are held in memory before fn is called.
+// On API request failure, this will return an error of type *RequestError.
+func (b *GetDocumentsBuilder) DoStream(ctx context.Context, fn func(api.Document) error) error {
+	if len(b.docIDs) == 0 {
+		return nil
+	}
+
+	req := b.buildRequest()
+	if b.maxChunkSize > 0 {
+		return b.eachChunk(ctx, func(raws []json.RawMessage) error {
+			return b.decodeEach(raws, fn)
+		})
+	}
+	if b.exceedsURLLength(req) {
+		raws, err := b.queryDocuments(ctx)
+		if err != nil {
+			return err
+		}
+		return b.decodeEach(raws, fn)
+	}
+
+	ctx, cancel := b.c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := b.c.send(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	stream := newArrayStream(resp.Body, "documents")
+	for {
+		raw, err := stream.next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var doc api.Document
+		if err := unmarshalJSON(raw, &doc, b.c.useNumber); err != nil {
+			return fmt.Errorf("decoding document: %w", err)
+		}
+
+		if err := fn(doc); err != nil {
+			return err
+		}
+	}
+}
+
+// decodeEach decodes the documents and calls fn with each of them.
+func (b *GetDocumentsBuilder) decodeEach(raws []json.RawMessage, fn func(api.Document) error) error {
+	for _, raw := range raws {
+		var doc api.Document
+		if err := unmarshalJSON(raw, &doc, b.c.useNumber); err != nil {
+			return fmt.Errorf("decoding document: %w", err)
+		}
+		if err := fn(doc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rawDocuments returns the documents as a JSON array.
+func (b *GetDocumentsBuilder) rawDocuments(ctx context.Context) (json.RawMessage, error) {
+	if len(b.docIDs) == 0 {
+		return json.RawMessage("[]"), nil
+	}
+
+	req := b.buildRequest()
+	if b.maxChunkSize > 0 || b.exceedsURLLength(req) {
+		raws, err := b.splitDocuments(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(raws)
+	}
+
+	var resp struct {
+		Documents json.RawMessage `json:"documents"`
+	}
+	if _, err := b.c.do(ctx, req, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Documents) == 0 || string(resp.Documents) == "null" {
+		return json.RawMessage("[]"), nil
+	}
+	return resp.Documents, nil
+}
+
+// exceedsURLLength reports whether the request would be too long to send, because of the
+// number or length of the IDs.
+func (b *GetDocumentsBuilder) exceedsURLLength(req *requests.Request) bool {
+	return len(req.EncodeURL()) > b.c.maxGETLength()
+}
+
+// queryDocuments fetches the documents with a *[_id in $ids] query sent as a POST request,
+// which has no limit on the number of IDs. The documents are returned in the order of the
+// requested IDs, leaving out those that do not exist, as the documents API does.
+func (b *GetDocumentsBuilder) queryDocuments(ctx context.Context) ([]json.RawMessage, error) {
+	// Like the documents API, return documents as stored, including drafts.
+	qb := b.c.storedQuery("*[_id in $ids]").Param("ids", b.docIDs).Tag(b.tag)
+
+	req, err := qb.buildPOST()
+	if err != nil {
+		return nil, err
+	}
+
+	var resp api.QueryResponse
+	if _, err := b.c.do(ctx, req, &resp); err != nil {
+		return nil, err
+	}
+
+	var raws []json.RawMessage
+	if resp.Result != nil {
+		if err := json.Unmarshal(*resp.Result, &raws); err != nil {
+			return nil, fmt.Errorf("decoding documents: %w", err)
+		}
+	}
+
+	byID := make(map[string]json.RawMessage, len(raws))
+	for _, raw := range raws {
+		var doc struct {
+			ID string `json:"_id"`
+		}
+		if err := json.Unmarshal(raw, &doc); err != nil {
+			return nil, fmt.Errorf("decoding document: %w", err)
+		}
+		byID[doc.ID] = raw
+	}
+
+	docs := make([]json.RawMessage, 0, len(raws))
+	for _, id := range b.docIDs {
+		if raw, ok := byID[id]; ok {
+			docs = append(docs, raw)
+			delete(byID, id)
+		}
+	}
+	return docs, nil
+}
+
+func (b *GetDocumentsBuilder) buildRequest() *requests.Request {
+	return b.c.newAPIRequest().
+		AppendPath("data/doc", b.c.dataset, strings.Join(b.docIDs, ",")).
+		Tag(b.tag, b.c.tag)
+}
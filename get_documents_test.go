@@ -2,11 +2,16 @@ package sanity_test
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/go-chi/chi"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -58,13 +63,41 @@ func TestGetDocuments(t *testing.T) {
 	})
 
 	t.Run("GET URL length exceeded", func(t *testing.T) {
+		ids := make([]string, 200)
+		for i := range ids {
+			ids[i] = fmt.Sprintf("document-%03d", i)
+		}
+
 		withSuite(t, func(s *Suite) {
-			docID := make([]rune, 1024)
-			for i := range docID {
-				docID[i] = 'x'
+			s.mux.Post("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				var req struct {
+					Query  string              `json:"query"`
+					Params map[string][]string `json:"params"`
+				}
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+				assert.Equal(t, "*[_id in $ids]", req.Query)
+				assert.Equal(t, ids, req.Params["ids"])
+
+				// Return the documents out of order, leaving one out.
+				docs := []api.Document{}
+				for i := len(ids) - 1; i > 0; i-- {
+					docs = append(docs, api.Document{"_id": ids[i]})
+				}
+				_, err := w.Write(mustJSONBytes(&api.QueryResponse{Result: mustJSONMsg(docs)}))
+				assert.NoError(t, err)
+			})
+
+			resp, err := s.client.GetDocuments(ids...).Do(context.Background())
+			require.NoError(t, err)
+			require.Len(t, resp.Documents, len(ids)-1)
+			for i, doc := range resp.Documents {
+				assert.Equal(t, ids[i+1], doc["_id"])
 			}
-			_, err := s.client.GetDocuments(string(docID)).Do(context.Background())
-			require.Error(t, err)
+
+			var typed []testDocument
+			require.NoError(t, s.client.GetDocuments(ids...).Into(context.Background(), &typed))
+			require.Len(t, typed, len(ids)-1)
+			assert.Equal(t, ids[1], typed[0].ID)
 		})
 	})
 
@@ -82,9 +115,22 @@ func TestGetDocuments(t *testing.T) {
 			require.NoError(t, err)
 
 			assert.Equal(t, testDocuments, result.Documents)
+
+			var typed []testDocument
+			require.NoError(t, result.Unmarshal(&typed))
+			assert.Equal(t, []testDocument{*testDoc1, *testDoc2}, typed)
 		})
 	})
 
+	t.Run("unmarshals no documents into empty slice", func(t *testing.T) {
+		typed := []testDocument{{ID: "stale"}}
+		require.NoError(t, (&api.GetDocumentsResponse{}).Unmarshal(&typed))
+		assert.Empty(t, typed)
+
+		var notSlice testDocument
+		require.Error(t, (&api.GetDocumentsResponse{Documents: testDocuments}).Unmarshal(&notSlice))
+	})
+
 	t.Run("supports default tag", func(t *testing.T) {
 		withSuite(t, func(s *Suite) {
 			s.mux.Get("/v1/data/doc/myDataset", func(w http.ResponseWriter, r *http.Request) {
@@ -106,4 +152,414 @@ func TestGetDocuments(t *testing.T) {
 			require.Error(t, err)
 		}, sanity.WithTag("tag"))
 	})
+
+	t.Run("tags fallback query", func(t *testing.T) {
+		ids := make([]string, 200)
+		for i := range ids {
+			ids[i] = fmt.Sprintf("document-%03d", i)
+		}
+
+		for _, tc := range []struct {
+			desc string
+			tag  string
+			want string
+		}{
+			{desc: "default", want: "default"},
+			{desc: "override", tag: "custom", want: "custom"},
+		} {
+			tc := tc
+			t.Run(tc.desc, func(t *testing.T) {
+				withSuite(t, func(s *Suite) {
+					s.mux.Post("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+						assert.Equal(t, tc.want, r.URL.Query().Get("tag"))
+						_, err := w.Write(mustJSONBytes(&api.QueryResponse{Result: mustJSONMsg([]string{})}))
+						assert.NoError(t, err)
+					})
+
+					_, err := s.client.GetDocuments(ids...).Tag(tc.tag).Do(context.Background())
+					require.NoError(t, err)
+				}, sanity.WithTag("default"))
+			})
+		}
+	})
+
+	t.Run("falls back to query regardless of exceed policy", func(t *testing.T) {
+		ids := make([]string, 200)
+		for i := range ids {
+			ids[i] = fmt.Sprintf("document-%03d", i)
+		}
+
+		withSuite(t, func(s *Suite) {
+			s.mux.Post("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				_, err := w.Write(mustJSONBytes(&api.QueryResponse{Result: mustJSONMsg([]api.Document{{"_id": ids[0]}})}))
+				assert.NoError(t, err)
+			})
+
+			result, err := s.client.GetDocuments(ids...).Do(context.Background())
+			require.NoError(t, err)
+			assert.Len(t, result.Documents, 1)
+		}, sanity.WithMaxQueryLength(1024, sanity.ExceedError))
+	})
+}
+
+func TestGetDocuments_DoStream(t *testing.T) {
+	docIDs := make([]string, 50)
+	for i := range docIDs {
+		docIDs[i] = fmt.Sprintf("d%d", i)
+	}
+
+	largeValue := strings.Repeat("x", 100000)
+
+	t.Run("streams documents in order", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/doc/myDataset/"+strings.Join(docIDs, ","), func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				_, err := w.Write([]byte(`{"omitted":[{"_id":"x"}],"documents":[`))
+				assert.NoError(t, err)
+				for i, id := range docIDs {
+					if i > 0 {
+						_, err = w.Write([]byte(","))
+						assert.NoError(t, err)
+					}
+					_, err = w.Write(mustJSONBytes(&testDocument{ID: id, Value: largeValue}))
+					assert.NoError(t, err)
+				}
+				_, err = w.Write([]byte(`]}`))
+				assert.NoError(t, err)
+			})
+
+			var got []string
+			err := s.client.GetDocuments(docIDs...).DoStream(context.Background(), func(doc api.Document) error {
+				assert.Equal(t, largeValue, doc["value"])
+				got = append(got, doc["_id"].(string))
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, docIDs, got)
+		})
+	})
+
+	t.Run("stops on callback error", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/doc/myDataset/doc1,doc2", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				_, err := w.Write([]byte(`{"documents":[{"_id":"doc1"},{"_id":"doc2"}]}`))
+				assert.NoError(t, err)
+			})
+
+			errStop := errors.New("stop")
+			calls := 0
+			err := s.client.GetDocuments("doc1", "doc2").DoStream(context.Background(), func(doc api.Document) error {
+				calls++
+				return errStop
+			})
+			require.True(t, errors.Is(err, errStop))
+			assert.Equal(t, 1, calls)
+		})
+	})
+
+	t.Run("handles missing documents", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/doc/myDataset/doc1", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				_, err := w.Write([]byte(`{"documents":[]}`))
+				assert.NoError(t, err)
+			})
+
+			err := s.client.GetDocuments("doc1").DoStream(context.Background(), func(doc api.Document) error {
+				t.Fatal("unexpected document")
+				return nil
+			})
+			require.NoError(t, err)
+		})
+	})
+
+	t.Run("reports truncated response", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/doc/myDataset/doc1", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				_, err := w.Write([]byte(`{"documents":[{"_id":"doc1"},{"_id":`))
+				assert.NoError(t, err)
+			})
+
+			err := s.client.GetDocuments("doc1").DoStream(context.Background(), func(doc api.Document) error {
+				return nil
+			})
+			require.Error(t, err)
+		})
+	})
+
+	t.Run("falls back to query for long ID lists", func(t *testing.T) {
+		ids := make([]string, 1000)
+		for i := range ids {
+			ids[i] = fmt.Sprintf("document-%04d", i)
+		}
+
+		withSuite(t, func(s *Suite) {
+			s.mux.Post("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				var req api.QueryRequest
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+				var reqIDs []string
+				require.NoError(t, json.Unmarshal(*req.Params["ids"], &reqIDs))
+				assert.Equal(t, ids, reqIDs)
+
+				docs := []api.Document{{"_id": ids[1]}, {"_id": ids[0]}}
+				_, err := w.Write(mustJSONBytes(&api.QueryResponse{Result: mustJSONMsg(docs)}))
+				assert.NoError(t, err)
+			})
+
+			var got []string
+			err := s.client.GetDocuments(ids...).DoStream(context.Background(), func(doc api.Document) error {
+				got = append(got, doc["_id"].(string))
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, ids[:2], got)
+		})
+	})
+
+	t.Run("fetches in chunks", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			var lengths []int
+			s.mux.Get("/v1/data/doc/myDataset/{ids}", func(w http.ResponseWriter, r *http.Request) {
+				reqIDs := strings.Split(chi.URLParam(r, "ids"), ",")
+				lengths = append(lengths, len(reqIDs))
+
+				docs := make([]api.Document, len(reqIDs))
+				for i, id := range reqIDs {
+					docs[i] = api.Document{"_id": id, "value": strings.Repeat("x", 1000)}
+				}
+				_, err := w.Write(mustJSONBytes(&api.GetDocumentsResponse{Documents: docs}))
+				assert.NoError(t, err)
+			})
+
+			var got []string
+			err := s.client.GetDocuments(docIDs...).ChunkResponseSize(3500).DoStream(context.Background(), func(doc api.Document) error {
+				got = append(got, doc["_id"].(string))
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, docIDs, got)
+			assert.Greater(t, len(lengths), 1)
+		})
+	})
+
+	t.Run("returns request error", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/doc/myDataset/doc1", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+			})
+
+			err := s.client.GetDocuments("doc1").DoStream(context.Background(), func(doc api.Document) error {
+				return nil
+			})
+			var reqErr *sanity.RequestError
+			require.True(t, errors.As(err, &reqErr))
+		})
+	})
+}
+
+func TestGetDocuments_Into(t *testing.T) {
+	now := time.Date(2020, 1, 2, 23, 01, 44, 0, time.UTC)
+	doc1 := testDocument{ID: "doc1", Type: "doc", CreatedAt: now, UpdatedAt: now, Value: "one"}
+	doc2 := testDocument{ID: "doc2", Type: "doc", CreatedAt: now, UpdatedAt: now, Value: "two"}
+
+	t.Run("decodes documents in order", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/doc/myDataset/doc2,doc1", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				_, err := w.Write(mustJSONBytes(map[string]interface{}{
+					"documents": []testDocument{doc2, doc1},
+				}))
+				assert.NoError(t, err)
+			})
+
+			var docs []testDocument
+			err := s.client.GetDocuments("doc2", "doc1").Into(context.Background(), &docs)
+			require.NoError(t, err)
+			assert.Equal(t, []testDocument{doc2, doc1}, docs)
+		})
+	})
+
+	t.Run("leaves slice empty when nothing is found", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/doc/myDataset/doc1", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				_, err := w.Write([]byte(`{"documents":[]}`))
+				assert.NoError(t, err)
+			})
+
+			var docs []testDocument
+			require.NoError(t, s.client.GetDocuments("doc1").Into(context.Background(), &docs))
+			assert.Empty(t, docs)
+
+			require.NoError(t, s.client.GetDocuments().Into(context.Background(), &docs))
+			assert.Empty(t, docs)
+		})
+	})
+}
+
+func TestGetRevision(t *testing.T) {
+	serve := func(t *testing.T, s *Suite, result interface{}) {
+		s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "*[_id == $id][0]._rev", r.URL.Query().Get("query"))
+			assert.Equal(t, `"doc1"`, r.URL.Query().Get("$id"))
+
+			w.WriteHeader(http.StatusOK)
+			_, err := w.Write(mustJSONBytes(&api.QueryResponse{Result: mustJSONMsg(result)}))
+			assert.NoError(t, err)
+		})
+	}
+
+	t.Run("existing document", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			serve(t, s, "rev1")
+			rev, err := s.client.GetRevision(context.Background(), "doc1")
+			require.NoError(t, err)
+			assert.Equal(t, "rev1", rev)
+		})
+	})
+
+	t.Run("missing document", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			serve(t, s, nil)
+			_, err := s.client.GetRevision(context.Background(), "doc1")
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, sanity.ErrDocumentNotFound))
+		})
+	})
+}
+
+func TestGetRevision_bypassesCDNAndPerspective(t *testing.T) {
+	mux := chi.NewRouter()
+	mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "raw", r.URL.Query().Get("perspective"))
+		_, err := w.Write(mustJSONBytes(&api.QueryResponse{Result: mustJSONMsg("rev1")}))
+		assert.NoError(t, err)
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	transport := &redirectTransport{server: server}
+	c, err := sanity.VersionV1.NewClient("myProject", "myDataset",
+		sanity.WithCDN(true),
+		sanity.WithPerspective("previewDrafts"),
+		sanity.WithHTTPClient(&http.Client{Transport: transport}))
+	require.NoError(t, err)
+
+	rev, err := c.GetRevision(context.Background(), "doc1")
+	require.NoError(t, err)
+	assert.Equal(t, "rev1", rev)
+	assert.Equal(t, []string{"myProject.api.sanity.io"}, transport.hosts)
+}
+
+func TestGetDocuments_chunkResponseSize(t *testing.T) {
+	ids := make([]string, 40)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("doc%02d", i)
+	}
+
+	// serve returns documents whose value is valueSize(id) bytes long, and records the number
+	// of IDs in each request.
+	serve := func(s *Suite, valueSize func(id string) int) *[]int {
+		var lengths []int
+		s.mux.Get("/v1/data/doc/myDataset/{ids}", func(w http.ResponseWriter, r *http.Request) {
+			reqIDs := strings.Split(chi.URLParam(r, "ids"), ",")
+			lengths = append(lengths, len(reqIDs))
+
+			docs := make([]api.Document, len(reqIDs))
+			for i, id := range reqIDs {
+				docs[i] = api.Document{"_id": id, "value": strings.Repeat("x", valueSize(id))}
+			}
+			_, err := w.Write(mustJSONBytes(&api.GetDocumentsResponse{Documents: docs}))
+			assert.NoError(t, err)
+		})
+		return &lengths
+	}
+
+	t.Run("reduces chunks of large documents", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			lengths := serve(s, func(string) int { return 1000 })
+
+			result, err := s.client.GetDocuments(ids...).ChunkResponseSize(3500).Do(context.Background())
+			require.NoError(t, err)
+
+			require.Len(t, result.Documents, len(ids))
+			for i, doc := range result.Documents {
+				assert.Equal(t, ids[i], doc["_id"])
+			}
+
+			assert.Equal(t, 10, (*lengths)[0])
+			for _, n := range (*lengths)[1:] {
+				assert.LessOrEqual(t, n, 3)
+			}
+		})
+	})
+
+	t.Run("grows chunks of small documents gradually", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			lengths := serve(s, func(string) int { return 10 })
+
+			var docs []map[string]interface{}
+			require.NoError(t, s.client.GetDocuments(ids...).ChunkResponseSize(1<<20).Into(context.Background(), &docs))
+
+			assert.Len(t, docs, len(ids))
+			assert.Equal(t, []int{10, 20, 10}, *lengths)
+		})
+	})
+
+	t.Run("adapts when documents grow", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			lengths := serve(s, func(id string) int {
+				if id < "doc10" {
+					return 10
+				}
+				return 2000
+			})
+
+			result, err := s.client.GetDocuments(ids...).ChunkResponseSize(5000).Do(context.Background())
+			require.NoError(t, err)
+			assert.Len(t, result.Documents, len(ids))
+
+			// The first chunk of small documents allows a larger second chunk, after which
+			// the chunks shrink to fit the large documents.
+			require.Greater(t, len(*lengths), 3)
+			assert.Equal(t, []int{10, 20}, (*lengths)[:2])
+			for _, n := range (*lengths)[2:] {
+				assert.LessOrEqual(t, n, 2)
+			}
+		})
+	})
+}
+
+func TestGetDocuments_jsonNumbers(t *testing.T) {
+	const large = `{"documents":[{"_id":"doc1","count":9007199254740993}]}`
+
+	for _, tc := range []struct {
+		desc   string
+		enable bool
+		want   interface{}
+	}{
+		{desc: "enabled", enable: true, want: json.Number("9007199254740993")},
+		{desc: "disabled", enable: false, want: float64(9007199254740992)},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			withSuite(t, func(s *Suite) {
+				s.mux.Get("/v1/data/doc/myDataset/doc1", func(w http.ResponseWriter, r *http.Request) {
+					_, err := w.Write([]byte(large))
+					assert.NoError(t, err)
+				})
+
+				result, err := s.client.GetDocuments("doc1").Do(context.Background())
+				require.NoError(t, err)
+				require.Len(t, result.Documents, 1)
+				assert.Equal(t, tc.want, result.Documents[0]["count"])
+
+				var docs []map[string]interface{}
+				require.NoError(t, s.client.GetDocuments("doc1").Into(context.Background(), &docs))
+				assert.Equal(t, tc.want, docs[0]["count"])
+			}, sanity.WithJSONNumbers(tc.enable))
+		})
+	}
 }
@@ -1,9 +1,15 @@
 module github.com/sanity-io/client-go
 
-go 1.13
+go 1.21
 
 require (
 	github.com/go-chi/chi v1.5.1
 	github.com/jpillora/backoff v0.0.0-20180909062703-3050d21c67d7
 	github.com/stretchr/testify v1.6.1
 )
+
+require (
+	github.com/davecgh/go-spew v1.1.0 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c // indirect
+)
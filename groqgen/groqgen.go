@@ -0,0 +1,260 @@
+// Package groqgen generates Go type definitions for GROQ query results.
+package groqgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/format"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// GenerateStruct returns the Go source of a type named Result that matches the shape of
+// sampleResult, a result of the query. If the result is an array, Result is the type of its
+// elements. Field types are inferred from all samples of a field, so a result with many
+// elements gives a more accurate type: a field that is an integer in some elements and a
+// fraction in others becomes a float64, and a field with conflicting types, or that is only
+// ever null, becomes an interface{}.
+func GenerateStruct(query string, sampleResult json.RawMessage) (string, error) {
+	dec := json.NewDecoder(bytes.NewReader(sampleResult))
+	dec.UseNumber()
+
+	s, err := parseShape(dec)
+	if err != nil {
+		return "", fmt.Errorf("parsing sample result: %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return "", errors.New("parsing sample result: unexpected data after result")
+	}
+
+	// The source is formatted as a file, since doc comments are only formatted as such in
+	// a complete file. The package clause is removed afterwards.
+	const pkg = "package result\n\n"
+
+	var buf bytes.Buffer
+	buf.WriteString(pkg)
+	desc := "the result"
+	if s.kind == kindArray {
+		s, desc = s.elem, "an element of the result"
+	}
+	fmt.Fprintf(&buf, "// Result is %s of the query:\n//\n", desc)
+	for _, line := range strings.Split(strings.TrimSpace(query), "\n") {
+		fmt.Fprintf(&buf, "//\t%s\n", line)
+	}
+	buf.WriteString("type Result ")
+	writeType(&buf, s)
+	buf.WriteString("\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("formatting source: %w", err)
+	}
+	return strings.TrimPrefix(string(src), pkg), nil
+}
+
+type kind int
+
+const (
+	kindNull kind = iota
+	kindBool
+	kindInt
+	kindFloat
+	kindString
+	kindObject
+	kindArray
+	kindMixed
+)
+
+// shape is the inferred type of a JSON value.
+type shape struct {
+	kind   kind
+	fields []*field // for objects, in order of first appearance
+	elem   *shape   // for arrays; nil if no element has been seen
+}
+
+type field struct {
+	key   string
+	shape *shape
+}
+
+func parseShape(dec *json.Decoder) (*shape, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch tok := tok.(type) {
+	case nil:
+		return &shape{kind: kindNull}, nil
+	case bool:
+		return &shape{kind: kindBool}, nil
+	case string:
+		return &shape{kind: kindString}, nil
+	case json.Number:
+		if _, err := tok.Int64(); err == nil {
+			return &shape{kind: kindInt}, nil
+		}
+		return &shape{kind: kindFloat}, nil
+	case json.Delim:
+		if tok == '[' {
+			s := &shape{kind: kindArray}
+			for dec.More() {
+				elem, err := parseShape(dec)
+				if err != nil {
+					return nil, err
+				}
+				s.elem = merge(s.elem, elem)
+			}
+			_, err := dec.Token()
+			return s, err
+		}
+
+		s := &shape{kind: kindObject}
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := parseShape(dec)
+			if err != nil {
+				return nil, err
+			}
+			s.addField(keyTok.(string), value)
+		}
+		_, err := dec.Token()
+		return s, err
+	}
+	return nil, fmt.Errorf("unexpected token %v", tok)
+}
+
+func (s *shape) addField(key string, value *shape) {
+	for _, f := range s.fields {
+		if f.key == key {
+			f.shape = merge(f.shape, value)
+			return
+		}
+	}
+	s.fields = append(s.fields, &field{key: key, shape: value})
+}
+
+// merge returns a shape that fits values of both shapes.
+func merge(a, b *shape) *shape {
+	switch {
+	case a == nil || a.kind == kindNull:
+		return b
+	case b == nil || b.kind == kindNull:
+		return a
+	case a.kind == b.kind:
+		switch a.kind {
+		case kindObject:
+			for _, f := range b.fields {
+				a.addField(f.key, f.shape)
+			}
+		case kindArray:
+			a.elem = merge(a.elem, b.elem)
+		}
+		return a
+	case (a.kind == kindInt || a.kind == kindFloat) && (b.kind == kindInt || b.kind == kindFloat):
+		return &shape{kind: kindFloat}
+	default:
+		return &shape{kind: kindMixed}
+	}
+}
+
+func writeType(buf *bytes.Buffer, s *shape) {
+	if s == nil {
+		buf.WriteString("interface{}")
+		return
+	}
+
+	switch s.kind {
+	case kindBool:
+		buf.WriteString("bool")
+	case kindInt:
+		buf.WriteString("int64")
+	case kindFloat:
+		buf.WriteString("float64")
+	case kindString:
+		buf.WriteString("string")
+	case kindArray:
+		buf.WriteString("[]")
+		writeType(buf, s.elem)
+	case kindObject:
+		buf.WriteString("struct {\n")
+		used := map[string]bool{}
+		for _, f := range s.fields {
+			name := uniqueName(fieldName(f.key), used)
+			fmt.Fprintf(buf, "%s ", name)
+			writeType(buf, f.shape)
+			fmt.Fprintf(buf, " `json:%q`\n", f.key)
+		}
+		buf.WriteString("}")
+	default:
+		buf.WriteString("interface{}")
+	}
+}
+
+// initialisms are words that Go spells in all capitals.
+var initialisms = map[string]bool{
+	"API": true, "HTML": true, "HTTP": true, "ID": true, "JSON": true, "SEO": true,
+	"SQL": true, "URI": true, "URL": true, "UUID": true, "XML": true,
+}
+
+// fieldName returns an exported Go identifier for a JSON key, such as ID for _id and
+// ImageURL for imageUrl or image_url.
+func fieldName(key string) string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+	}
+	for _, r := range key {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && len(word) > 0 && !unicode.IsUpper(word[len(word)-1]):
+			flush()
+			word = append(word, r)
+		default:
+			word = append(word, r)
+		}
+	}
+	flush()
+
+	var sb strings.Builder
+	for _, w := range words {
+		if upper := strings.ToUpper(w); initialisms[upper] {
+			sb.WriteString(upper)
+			continue
+		}
+		rs := []rune(w)
+		rs[0] = unicode.ToUpper(rs[0])
+		sb.WriteString(string(rs))
+	}
+
+	name := sb.String()
+	if name == "" {
+		return "Field"
+	}
+	if unicode.IsDigit([]rune(name)[0]) {
+		return "F" + name
+	}
+	return name
+}
+
+// uniqueName returns name, or name with a numeric suffix if it is already used, such as
+// when a result has both _type and type.
+func uniqueName(name string, used map[string]bool) string {
+	unique := name
+	for i := 2; used[unique]; i++ {
+		unique = fmt.Sprintf("%s%d", name, i)
+	}
+	used[unique] = true
+	return unique
+}
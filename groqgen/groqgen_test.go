@@ -0,0 +1,96 @@
+package groqgen_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/sanity-io/client-go/groqgen"
+)
+
+func TestGenerateStruct(t *testing.T) {
+	for _, tc := range []struct {
+		desc   string
+		query  string
+		sample string
+		want   string
+	}{
+		{
+			desc:   "object",
+			query:  `*[_id == "a"][0]{_id, title, views, rating, published}`,
+			sample: `{"_id":"a","title":"Hello","views":3,"rating":4.5,"published":true}`,
+			want: "// Result is the result of the query:\n" +
+				"//\n" +
+				"//\t*[_id == \"a\"][0]{_id, title, views, rating, published}\n" +
+				"type Result struct {\n" +
+				"\tID        string  `json:\"_id\"`\n" +
+				"\tTitle     string  `json:\"title\"`\n" +
+				"\tViews     int64   `json:\"views\"`\n" +
+				"\tRating    float64 `json:\"rating\"`\n" +
+				"\tPublished bool    `json:\"published\"`\n" +
+				"}\n",
+		},
+		{
+			desc:  "array with nested objects and arrays",
+			query: `*[_type == "post"]{title, author->{name, imageUrl}, tags, score}`,
+			sample: `[
+				{"title":"A","author":{"name":"X","imageUrl":"u"},"tags":["a"],"score":1},
+				{"title":"B","author":{"name":"Y"},"tags":[],"score":1.5,"extra":null}
+			]`,
+			want: "// Result is an element of the result of the query:\n" +
+				"//\n" +
+				"//\t*[_type == \"post\"]{title, author->{name, imageUrl}, tags, score}\n" +
+				"type Result struct {\n" +
+				"\tTitle  string `json:\"title\"`\n" +
+				"\tAuthor struct {\n" +
+				"\t\tName     string `json:\"name\"`\n" +
+				"\t\tImageURL string `json:\"imageUrl\"`\n" +
+				"\t} `json:\"author\"`\n" +
+				"\tTags  []string    `json:\"tags\"`\n" +
+				"\tScore float64     `json:\"score\"`\n" +
+				"\tExtra interface{} `json:\"extra\"`\n" +
+				"}\n",
+		},
+		{
+			desc:   "array of arrays of objects with colliding names",
+			query:  `*[]{_type, type, "rows": cells[]}`,
+			sample: `[{"_type":"t","type":"u","rows":[[{"n":1}],[{"n":"x"}]]}]`,
+			want: "// Result is an element of the result of the query:\n" +
+				"//\n" +
+				"//\t*[]{_type, type, \"rows\": cells[]}\n" +
+				"type Result struct {\n" +
+				"\tType  string `json:\"_type\"`\n" +
+				"\tType2 string `json:\"type\"`\n" +
+				"\tRows  [][]struct {\n" +
+				"\t\tN interface{} `json:\"n\"`\n" +
+				"\t} `json:\"rows\"`\n" +
+				"}\n",
+		},
+		{
+			desc:   "scalar",
+			query:  `count(*)`,
+			sample: `42`,
+			want: "// Result is the result of the query:\n" +
+				"//\n" +
+				"//\tcount(*)\n" +
+				"type Result int64\n",
+		},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			src, err := groqgen.GenerateStruct(tc.query, json.RawMessage(tc.sample))
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, src)
+		})
+	}
+
+	t.Run("invalid sample", func(t *testing.T) {
+		_, err := groqgen.GenerateStruct("*", json.RawMessage(`{"a":`))
+		require.Error(t, err)
+
+		_, err = groqgen.GenerateStruct("*", json.RawMessage(`1 2`))
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,70 @@
+package sanity
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/sanity-io/client-go/api"
+)
+
+// History returns a new builder for fetching a previous version of a document.
+func (c *Client) History(docID string) *HistoryBuilder {
+	return &HistoryBuilder{c: c, docID: docID}
+}
+
+// HistoryBuilder is a builder for the document history API.
+type HistoryBuilder struct {
+	c        *Client
+	docID    string
+	revision string
+	at       time.Time
+	tag      string
+}
+
+// Revision selects the version of the document with the given revision ID.
+func (b *HistoryBuilder) Revision(rev string) *HistoryBuilder {
+	b.revision = rev
+	return b
+}
+
+// At selects the version of the document as it was at the given time.
+func (b *HistoryBuilder) At(t time.Time) *HistoryBuilder {
+	b.at = t
+	return b
+}
+
+// Tag sets the request tag, overriding the client default set with WithTag.
+func (b *HistoryBuilder) Tag(tag string) *HistoryBuilder {
+	b.tag = tag
+	return b
+}
+
+// Do fetches the document version. The response holds a single document, or none if the
+// document did not exist at the selected revision or time.
+// On API request failure, this will return an error of type *RequestError.
+func (b *HistoryBuilder) Do(ctx context.Context) (*api.GetDocumentsResponse, error) {
+	if b.docID == "" {
+		return nil, errors.New("document ID must be set")
+	}
+	if b.revision != "" && !b.at.IsZero() {
+		return nil, errors.New("revision and time cannot both be set")
+	}
+
+	req := b.c.newAPIRequest().
+		AppendPath("data/history", b.c.dataset, "documents", b.docID).
+		Tag(b.tag, b.c.tag)
+	if b.revision != "" {
+		req.Param("revision", b.revision)
+	}
+	if !b.at.IsZero() {
+		req.Param("time", b.at.UTC().Format(time.RFC3339Nano))
+	}
+
+	var resp api.GetDocumentsResponse
+	if _, err := b.c.do(ctx, req, &resp); err != nil {
+		return nil, err
+	}
+
+	return &resp, nil
+}
@@ -0,0 +1,87 @@
+package sanity_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sanity "github.com/sanity-io/client-go"
+	"github.com/sanity-io/client-go/api"
+)
+
+func TestHistory(t *testing.T) {
+	now := time.Date(2020, 1, 2, 23, 01, 44, 0, time.UTC)
+	testDoc := &testDocument{
+		ID:        "doc1",
+		Type:      "doc",
+		CreatedAt: now,
+		UpdatedAt: now,
+		Value:     "hello world",
+	}
+
+	t.Run("by revision", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/history/myDataset/documents/doc1", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "rev1", r.URL.Query().Get("revision"))
+				assert.Equal(t, "", r.URL.Query().Get("time"))
+
+				w.WriteHeader(http.StatusOK)
+				_, err := w.Write(mustJSONBytes(&api.GetDocumentsResponse{
+					Documents: []api.Document{testDoc.toMap()},
+				}))
+				assert.NoError(t, err)
+			})
+
+			result, err := s.client.History("doc1").Revision("rev1").Do(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, []api.Document{testDoc.toMap()}, result.Documents)
+		})
+	})
+
+	t.Run("by time", func(t *testing.T) {
+		at := time.Date(2020, 1, 2, 10, 0, 0, 0, time.FixedZone("CET", 3600))
+
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/history/myDataset/documents/doc1", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "2020-01-02T09:00:00Z", r.URL.Query().Get("time"))
+				assert.Equal(t, "", r.URL.Query().Get("revision"))
+
+				w.WriteHeader(http.StatusOK)
+				_, err := w.Write(mustJSONBytes(&api.GetDocumentsResponse{
+					Documents: []api.Document{testDoc.toMap()},
+				}))
+				assert.NoError(t, err)
+			})
+
+			result, err := s.client.History("doc1").At(at).Do(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, []api.Document{testDoc.toMap()}, result.Documents)
+		})
+	})
+
+	t.Run("revision and time are exclusive", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			_, err := s.client.History("doc1").Revision("rev1").At(now).Do(context.Background())
+			require.Error(t, err)
+		})
+	})
+
+	t.Run("returns request error", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/history/myDataset/documents/doc1", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+			})
+
+			_, err := s.client.History("doc1").Revision("rev1").Do(context.Background())
+			require.Error(t, err)
+
+			var reqErr *sanity.RequestError
+			require.True(t, errors.As(err, &reqErr))
+		})
+	})
+}
@@ -2,11 +2,15 @@ package requests
 
 import (
 	"bytes"
+	"compress/gzip"
+	"encoding"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 )
 
 type Request struct {
@@ -17,6 +21,12 @@ type Request struct {
 	body            io.Reader
 	headers         http.Header
 	maxResponseSize int64
+	idempotent      bool
+	gzip            bool
+	gzipMinSize     int
+	explicitTag     bool
+	dataset         string
+	cleanPath       bool
 	err             error
 }
 
@@ -35,7 +45,12 @@ func (b *Request) HTTPRequest() (*http.Request, error) {
 		return nil, b.err
 	}
 
-	req, err := http.NewRequest(b.method, b.EncodeURL(), b.body)
+	body, compressed, err := b.encodeBody()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(b.method, b.EncodeURL(), body)
 	if err != nil {
 		return nil, err
 	}
@@ -43,12 +58,43 @@ func (b *Request) HTTPRequest() (*http.Request, error) {
 	for k, v := range b.headers {
 		req.Header[k] = v
 	}
+	if compressed {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
 	return req, nil
 }
 
+// encodeBody returns the body to send, compressed if gzip is enabled and the body is
+// large enough. Only bodies set with Body or MarshalBody can be compressed.
+func (b *Request) encodeBody() (io.Reader, bool, error) {
+	r, ok := b.body.(*bytes.Reader)
+	if !b.gzip || !ok || r.Len() < b.gzipMinSize {
+		return b.body, false, nil
+	}
+
+	// Read without consuming the reader, so that the request can be built again.
+	raw := make([]byte, r.Len())
+	if _, err := r.ReadAt(raw, r.Size()-int64(r.Len())); err != nil && err != io.EOF {
+		return nil, false, fmt.Errorf("reading body: %w", err)
+	}
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(raw); err != nil {
+		return nil, false, fmt.Errorf("compressing body: %w", err)
+	}
+	if err := zw.Close(); err != nil {
+		return nil, false, fmt.Errorf("compressing body: %w", err)
+	}
+	return bytes.NewReader(buf.Bytes()), true, nil
+}
+
 func (b *Request) EncodeURL() string {
 	u := b.baseURL
 	u.Path += b.path
+	if b.cleanPath {
+		u.Path = collapseSlashes(u.Path)
+	}
 	if b.params != nil {
 		u.RawQuery = b.params.Encode()
 	}
@@ -84,6 +130,15 @@ func (b *Request) Header(name, val string) *Request {
 	return b
 }
 
+// SetHeader sets a header, replacing any values already added for it.
+func (b *Request) SetHeader(name, val string) *Request {
+	if b.headers == nil {
+		b.headers = make(http.Header, 10) // Small capacity
+	}
+	b.headers.Set(name, val)
+	return b
+}
+
 func (b *Request) Param(name string, val interface{}) *Request {
 	if b.params == nil {
 		b.params = make(url.Values, 10) // Small capacity
@@ -92,6 +147,13 @@ func (b *Request) Param(name string, val interface{}) *Request {
 	switch val := val.(type) {
 	case string:
 		b.params.Add(name, val)
+	case encoding.TextMarshaler:
+		text, err := val.MarshalText()
+		if err != nil {
+			b.setErr(fmt.Errorf("marshaling parameter %q: %w", name, err))
+			return b
+		}
+		b.params.Add(name, string(text))
 	case fmt.Stringer:
 		b.params.Add(name, val.String())
 	case bool:
@@ -100,8 +162,32 @@ func (b *Request) Param(name string, val interface{}) *Request {
 		} else {
 			b.params.Add(name, "false")
 		}
+	case int:
+		b.params.Add(name, strconv.Itoa(val))
+	case int8:
+		b.params.Add(name, strconv.FormatInt(int64(val), 10))
+	case int16:
+		b.params.Add(name, strconv.FormatInt(int64(val), 10))
+	case int32:
+		b.params.Add(name, strconv.FormatInt(int64(val), 10))
+	case int64:
+		b.params.Add(name, strconv.FormatInt(val, 10))
+	case uint:
+		b.params.Add(name, strconv.FormatUint(uint64(val), 10))
+	case uint8:
+		b.params.Add(name, strconv.FormatUint(uint64(val), 10))
+	case uint16:
+		b.params.Add(name, strconv.FormatUint(uint64(val), 10))
+	case uint32:
+		b.params.Add(name, strconv.FormatUint(uint64(val), 10))
+	case uint64:
+		b.params.Add(name, strconv.FormatUint(val, 10))
+	case float32:
+		b.params.Add(name, strconv.FormatFloat(float64(val), 'f', -1, 32))
+	case float64:
+		b.params.Add(name, strconv.FormatFloat(val, 'f', -1, 64))
 	default:
-		panic(fmt.Sprintf("cannot add %q of type %T as parameter", name, val))
+		b.setErr(fmt.Errorf("cannot add %q of type %T as parameter", name, val))
 	}
 	return b
 }
@@ -109,17 +195,58 @@ func (b *Request) Param(name string, val interface{}) *Request {
 func (b *Request) Tag(tag string, defaultTag string) *Request {
 	if tag != "" {
 		b.Param("tag", tag)
+		b.explicitTag = true
 	} else if defaultTag != "" {
 		b.Param("tag", defaultTag)
 	}
 	return b
 }
 
+// InheritTag sets the tag unless one was given explicitly to Tag, replacing any default.
+func (b *Request) InheritTag(tag string) *Request {
+	if tag == "" || b.explicitTag {
+		return b
+	}
+	if b.params == nil {
+		b.params = make(url.Values, 10) // Small capacity
+	}
+	b.params.Set("tag", tag)
+	return b
+}
+
+// Idempotent marks the request as safe to retry even if its method is not, such as a
+// mutation carrying a transaction ID.
+func (b *Request) Idempotent(enable bool) *Request {
+	b.idempotent = enable
+	return b
+}
+
+func (b *Request) IsIdempotent() bool {
+	return b.idempotent
+}
+
+// Dataset records the name of the dataset the request targets, for reporting in errors.
+func (b *Request) Dataset(name string) *Request {
+	b.dataset = name
+	return b
+}
+
+func (b *Request) DatasetName() string {
+	return b.dataset
+}
+
 func (b *Request) MaxResponseSize(limit int64) *Request {
 	b.maxResponseSize = limit
 	return b
 }
 
+// Gzip enables gzip compression of request bodies of at least minSize bytes.
+func (b *Request) Gzip(minSize int) *Request {
+	b.gzip = true
+	b.gzipMinSize = minSize
+	return b
+}
+
 func (b *Request) Body(body []byte) *Request {
 	b.body = bytes.NewReader(body)
 	return b
@@ -133,10 +260,44 @@ func (b *Request) ReadBody(r io.Reader) *Request {
 func (b *Request) MarshalBody(val interface{}) *Request {
 	body, err := json.Marshal(val)
 	if err != nil {
-		b.err = fmt.Errorf("marshaling body value to JSON: %w", err)
+		b.setErr(fmt.Errorf("marshaling body value to JSON: %w", err))
 		return b
 	}
 
 	b.body = bytes.NewReader(body)
 	return b
 }
+
+// CleanPath makes EncodeURL collapse repeated slashes in the path of the URL, such as those
+// produced by joining a base path that ends in a slash with a path that starts with one, or
+// by empty path elements. Unlike path.Clean, dot segments and trailing slashes are left
+// untouched.
+func (b *Request) CleanPath() *Request {
+	b.cleanPath = true
+	return b
+}
+
+// collapseSlashes replaces each run of slashes in p with a single slash.
+func collapseSlashes(p string) string {
+	if !strings.Contains(p, "//") {
+		return p
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(p))
+	for i := 0; i < len(p); i++ {
+		if p[i] == '/' && i > 0 && p[i-1] == '/' {
+			continue
+		}
+		sb.WriteByte(p[i])
+	}
+	return sb.String()
+}
+
+// setErr records the first error encountered while building the request. It is
+// returned by HTTPRequest.
+func (b *Request) setErr(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
@@ -1,7 +1,12 @@
 package requests_test
 
 import (
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+	"math"
 	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -70,3 +75,215 @@ func TestRequest_AppendPath(t *testing.T) {
 		})
 	}
 }
+
+func TestRequest_EncodeURL_cleanPath(t *testing.T) {
+	tests := []struct {
+		name     string
+		basePath string
+		elems    []string
+		want     string
+	}{
+		{
+			name:     "leading empty elements",
+			basePath: "/v1",
+			elems:    []string{"", "", "foo"},
+			want:     "https://localhost/v1/foo",
+		},
+		{
+			name:     "base path with trailing slash",
+			basePath: "/v1/",
+			elems:    []string{"foo"},
+			want:     "https://localhost/v1/foo",
+		},
+		{
+			name:     "base path with trailing slash and leading slash element",
+			basePath: "/v1/",
+			elems:    []string{"/foo"},
+			want:     "https://localhost/v1/foo",
+		},
+		{
+			name:     "repeated slashes within an element",
+			basePath: "/v1",
+			elems:    []string{"foo//bar", "baz"},
+			want:     "https://localhost/v1/foo/bar/baz",
+		},
+		{
+			name:     "trailing slash is preserved",
+			basePath: "/v1",
+			elems:    []string{"foo/"},
+			want:     "https://localhost/v1/foo/",
+		},
+		{
+			name:     "only empty elements",
+			basePath: "/v1",
+			elems:    []string{"", ""},
+			want:     "https://localhost/v1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := requests.New(url.URL{Scheme: "https", Host: "localhost", Path: tt.basePath})
+			require.Equal(t, tt.want, r.AppendPath(tt.elems...).CleanPath().EncodeURL())
+		})
+	}
+
+	t.Run("path is left untouched without CleanPath", func(t *testing.T) {
+		r := requests.New(url.URL{Scheme: "https", Host: "localhost", Path: "/v1/"})
+		require.Equal(t, "https://localhost/v1//foo", r.AppendPath("/foo").EncodeURL())
+	})
+}
+
+type textValue string
+
+func (v textValue) MarshalText() ([]byte, error) {
+	return []byte("text:" + string(v)), nil
+}
+
+type failingTextValue struct{}
+
+func (failingTextValue) MarshalText() ([]byte, error) {
+	return nil, errors.New("failure")
+}
+
+type stringerValue struct{}
+
+func (stringerValue) String() string {
+	return "stringer"
+}
+
+func TestRequest_Param(t *testing.T) {
+	tests := []struct {
+		name string
+		val  interface{}
+		want string
+	}{
+		{name: "string", val: "foo", want: "foo"},
+		{name: "text marshaler", val: textValue("foo"), want: "text:foo"},
+		{name: "stringer", val: stringerValue{}, want: "stringer"},
+		{name: "bool", val: true, want: "true"},
+		{name: "int", val: 42, want: "42"},
+		{name: "uint", val: uint(42), want: "42"},
+		{name: "float", val: 1.5, want: "1.5"},
+	}
+	baseURL := url.URL{Host: "localhost"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := requests.New(baseURL).Param("val", tt.val)
+			req, err := r.HTTPRequest()
+			require.NoError(t, err)
+			require.Equal(t, tt.want, req.URL.Query().Get("val"))
+		})
+	}
+
+	t.Run("numeric formatting", func(t *testing.T) {
+		for _, tt := range []struct {
+			name string
+			val  interface{}
+			want string
+		}{
+			{name: "int", val: -12, want: "-12"},
+			{name: "int8", val: int8(-8), want: "-8"},
+			{name: "int16", val: int16(1600), want: "1600"},
+			{name: "int32", val: int32(-32), want: "-32"},
+			{name: "large int64", val: int64(9007199254740993), want: "9007199254740993"},
+			{name: "max int64", val: int64(math.MaxInt64), want: "9223372036854775807"},
+			{name: "uint8", val: uint8(255), want: "255"},
+			{name: "uint16", val: uint16(65535), want: "65535"},
+			{name: "uint32", val: uint32(7), want: "7"},
+			{name: "max uint64", val: uint64(math.MaxUint64), want: "18446744073709551615"},
+			{name: "whole float", val: 1.0, want: "1"},
+			{name: "fractional float", val: 0.1, want: "0.1"},
+			{name: "large float", val: 1e21, want: "1000000000000000000000"},
+			{name: "float32", val: float32(1.1), want: "1.1"},
+		} {
+			t.Run(tt.name, func(t *testing.T) {
+				r := requests.New(baseURL).Param("val", tt.val)
+				req, err := r.HTTPRequest()
+				require.NoError(t, err)
+				require.Equal(t, tt.want, req.URL.Query().Get("val"))
+			})
+		}
+	})
+
+	t.Run("unsupported type", func(t *testing.T) {
+		var r *requests.Request
+		require.NotPanics(t, func() {
+			r = requests.New(baseURL).Param("val", struct{}{})
+		})
+		_, err := r.HTTPRequest()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), `"val"`)
+	})
+
+	t.Run("first error is kept", func(t *testing.T) {
+		r := requests.New(baseURL).
+			Param("first", struct{}{}).
+			Param("second", []int{}).
+			MarshalBody(map[string]interface{}{"x": 1})
+		_, err := r.HTTPRequest()
+		require.Error(t, err)
+		require.Contains(t, err.Error(), `"first"`)
+	})
+
+	t.Run("text marshaler failure", func(t *testing.T) {
+		r := requests.New(baseURL).Param("val", failingTextValue{})
+		_, err := r.HTTPRequest()
+		require.Error(t, err)
+	})
+}
+
+func TestRequest_InheritTag(t *testing.T) {
+	baseURL := url.URL{Host: "localhost"}
+	for _, tt := range []struct {
+		name       string
+		tag        string
+		defaultTag string
+		inherited  string
+		want       string
+	}{
+		{name: "replaces default", defaultTag: "default", inherited: "ctx", want: "ctx"},
+		{name: "sets when unset", inherited: "ctx", want: "ctx"},
+		{name: "keeps explicit", tag: "explicit", defaultTag: "default", inherited: "ctx", want: "explicit"},
+		{name: "keeps default when empty", defaultTag: "default", want: "default"},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := requests.New(baseURL).Tag(tt.tag, tt.defaultTag).InheritTag(tt.inherited)
+			req, err := r.HTTPRequest()
+			require.NoError(t, err)
+			require.Equal(t, []string{tt.want}, req.URL.Query()["tag"])
+		})
+	}
+}
+
+func TestRequest_Gzip(t *testing.T) {
+	body := []byte(`{"mutations":[` + strings.Repeat(`{"delete":{"id":"x"}},`, 100) + `{}]}`)
+
+	t.Run("compresses large body", func(t *testing.T) {
+		rb := requests.New(url.URL{Host: "localhost"}).Body(body).Gzip(100)
+		req, err := rb.HTTPRequest()
+		require.NoError(t, err)
+		require.Equal(t, "gzip", req.Header.Get("Content-Encoding"))
+
+		zr, err := gzip.NewReader(req.Body)
+		require.NoError(t, err)
+		got, err := ioutil.ReadAll(zr)
+		require.NoError(t, err)
+		require.Equal(t, string(body), string(got))
+
+		// Building the request again yields the same body.
+		again, err := rb.HTTPRequest()
+		require.NoError(t, err)
+		require.Equal(t, req.ContentLength, again.ContentLength)
+		require.Less(t, req.ContentLength, int64(len(body)))
+	})
+
+	t.Run("leaves small body uncompressed", func(t *testing.T) {
+		req, err := requests.New(url.URL{Host: "localhost"}).Body(body).Gzip(len(body) + 1).HTTPRequest()
+		require.NoError(t, err)
+		require.Empty(t, req.Header.Get("Content-Encoding"))
+
+		got, err := ioutil.ReadAll(req.Body)
+		require.NoError(t, err)
+		require.Equal(t, string(body), string(got))
+	})
+}
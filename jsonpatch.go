@@ -0,0 +1,152 @@
+package sanity
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sanity-io/client-go/api"
+)
+
+// FromJSONPatch returns the mutations that patch the document with the given ID in a way
+// that is equivalent to the given RFC 6902 JSON Patch document. Each operation becomes a
+// separate patch, so that operations are applied in order within the same transaction.
+//
+// JSON Pointer paths are translated to Sanity paths, so that /a/b/0 becomes a.b[0]. The
+// operations are translated as follows:
+//
+//   - add sets the value, except when the last path token is an array index or "-", in
+//     which case the value is inserted before that index or appended to the array.
+//   - remove unsets the value.
+//   - replace sets the value.
+//
+// The move, copy and test operations cannot be expressed as Sanity patches and result in
+// an error, as does an operation targeting the document root or an array element at the
+// root, since a document is an object.
+//
+// Unlike Client.FromJSONPatch, this returns the mutations rather than a *MutationBuilder,
+// since a mutation builder can only be sent through the client that created it. The
+// mutations can be applied locally with ApplyMutations or included in a MigrationPlan; to
+// send them, use Client.FromJSONPatch, which returns a mutation builder instead.
+func FromJSONPatch(id string, patch []byte) ([]*api.MutationItem, error) {
+	mb := &MutationBuilder{}
+	if err := mb.addJSONPatch(id, patch); err != nil {
+		return nil, err
+	}
+	return mb.items, nil
+}
+
+// FromJSONPatch returns a mutation builder with the patches returned by the package-level
+// FromJSONPatch, ready to be sent with Do.
+func (c *Client) FromJSONPatch(id string, patch []byte) (*MutationBuilder, error) {
+	mb := c.Mutate()
+	if err := mb.addJSONPatch(id, patch); err != nil {
+		return nil, err
+	}
+	return mb, nil
+}
+
+// addJSONPatch adds patches translated from a JSON Patch document.
+func (mb *MutationBuilder) addJSONPatch(id string, patch []byte) error {
+	var ops []jsonPatchOperation
+	if err := json.Unmarshal(patch, &ops); err != nil {
+		return fmt.Errorf("parsing JSON patch: %w", err)
+	}
+
+	for i, op := range ops {
+		tokens, err := parseJSONPointer(op.Path)
+		if err != nil {
+			return fmt.Errorf("operation %d: %w", i, err)
+		}
+		if len(tokens) == 0 {
+			return fmt.Errorf("operation %d: cannot %s the document root", i, op.Op)
+		}
+		if len(tokens) == 1 && (tokens[0] == "-" || isArrayIndex(tokens[0])) {
+			return fmt.Errorf("operation %d: cannot %s an array element of the document root", i, op.Op)
+		}
+
+		switch op.Op {
+		case "add":
+			if len(op.Value) == 0 {
+				return fmt.Errorf("operation %d: add requires a value", i)
+			}
+
+			parent, last := tokens[:len(tokens)-1], tokens[len(tokens)-1]
+			switch {
+			case last == "-":
+				mb.Patch(id).InsertAfter(sanityPath(parent)+"[-1]", op.Value)
+			case isArrayIndex(last):
+				mb.Patch(id).InsertBefore(sanityPath(tokens), op.Value)
+			default:
+				mb.Patch(id).Set(sanityPath(tokens), op.Value)
+			}
+		case "remove":
+			mb.Patch(id).Unset(sanityPath(tokens))
+		case "replace":
+			if len(op.Value) == 0 {
+				return fmt.Errorf("operation %d: replace requires a value", i)
+			}
+			mb.Patch(id).Set(sanityPath(tokens), op.Value)
+		case "move", "copy", "test":
+			return fmt.Errorf("operation %d: %q operations are not supported", i, op.Op)
+		default:
+			return fmt.Errorf("operation %d: unknown operation %q", i, op.Op)
+		}
+	}
+	return mb.err
+}
+
+type jsonPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	Value json.RawMessage `json:"value"`
+}
+
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("invalid JSON pointer %q", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+	return tokens, nil
+}
+
+var regExpIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func sanityPath(tokens []string) string {
+	var sb strings.Builder
+	for _, token := range tokens {
+		switch {
+		case isArrayIndex(token):
+			sb.WriteString("[" + token + "]")
+		case regExpIdentifier.MatchString(token):
+			if sb.Len() > 0 {
+				sb.WriteByte('.')
+			}
+			sb.WriteString(token)
+		default:
+			sb.WriteString("[" + strconv.Quote(token) + "]")
+		}
+	}
+	return sb.String()
+}
+
+func isArrayIndex(token string) bool {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return false
+	}
+	for _, r := range token {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,129 @@
+package sanity_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sanity "github.com/sanity-io/client-go"
+	"github.com/sanity-io/client-go/api"
+)
+
+func TestFromJSONPatch(t *testing.T) {
+	for _, tc := range []struct {
+		desc   string
+		patch  string
+		expect []*api.MutationItem
+	}{
+		{
+			"add and replace fields",
+			`[
+				{"op": "add", "path": "/title", "value": "Hello"},
+				{"op": "add", "path": "/subtitle", "value": null},
+				{"op": "replace", "path": "/author/name", "value": "Jane"}
+			]`,
+			[]*api.MutationItem{
+				{Patch: &api.Patch{ID: "123", Set: map[string]*json.RawMessage{"title": mustJSONMsg("Hello")}}},
+				{Patch: &api.Patch{ID: "123", Set: map[string]*json.RawMessage{"subtitle": nil}}},
+				{Patch: &api.Patch{ID: "123", Set: map[string]*json.RawMessage{"author.name": mustJSONMsg("Jane")}}},
+			},
+		},
+		{
+			"remove fields and array items",
+			`[
+				{"op": "remove", "path": "/subtitle"},
+				{"op": "remove", "path": "/tags/2"}
+			]`,
+			[]*api.MutationItem{
+				{Patch: &api.Patch{ID: "123", Unset: []string{"subtitle"}}},
+				{Patch: &api.Patch{ID: "123", Unset: []string{"tags[2]"}}},
+			},
+		},
+		{
+			"add to arrays",
+			`[
+				{"op": "add", "path": "/tags/-", "value": "last"},
+				{"op": "add", "path": "/tags/0", "value": "first"},
+				{"op": "replace", "path": "/tags/1", "value": "second"}
+			]`,
+			[]*api.MutationItem{
+				{Patch: &api.Patch{ID: "123", Insert: &api.Insert{
+					After: "tags[-1]",
+					Items: []*json.RawMessage{mustJSONMsg("last")},
+				}}},
+				{Patch: &api.Patch{ID: "123", Insert: &api.Insert{
+					Before: "tags[0]",
+					Items:  []*json.RawMessage{mustJSONMsg("first")},
+				}}},
+				{Patch: &api.Patch{ID: "123", Set: map[string]*json.RawMessage{"tags[1]": mustJSONMsg("second")}}},
+			},
+		},
+		{
+			"escaped and non-identifier keys",
+			`[
+				{"op": "replace", "path": "/a~1b/c~0d/e f", "value": 1}
+			]`,
+			[]*api.MutationItem{
+				{Patch: &api.Patch{ID: "123", Set: map[string]*json.RawMessage{
+					`["a/b"]["c~d"]["e f"]`: mustJSONMsg(1),
+				}}},
+			},
+		},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			withSuite(t, func(s *Suite) {
+				s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+					var req api.MutateRequest
+					require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+					assert.Equal(t, tc.expect, req.Mutations)
+
+					w.WriteHeader(http.StatusOK)
+					_, err := w.Write(mustJSONBytes(&api.MutateResponse{}))
+					assert.NoError(t, err)
+				})
+
+				mb, err := s.client.FromJSONPatch("123", []byte(tc.patch))
+				require.NoError(t, err)
+
+				_, err = mb.Do(context.Background())
+				require.NoError(t, err)
+			})
+
+			items, err := sanity.FromJSONPatch("123", []byte(tc.patch))
+			require.NoError(t, err)
+			assert.JSONEq(t, string(mustJSONBytes(tc.expect)), string(mustJSONBytes(items)))
+		})
+	}
+
+	for _, tc := range []struct {
+		desc  string
+		patch string
+	}{
+		{"move", `[{"op": "move", "from": "/a", "path": "/b"}]`},
+		{"copy", `[{"op": "copy", "from": "/a", "path": "/b"}]`},
+		{"test", `[{"op": "test", "path": "/a", "value": 1}]`},
+		{"unknown", `[{"op": "frobnicate", "path": "/a"}]`},
+		{"root", `[{"op": "replace", "path": "", "value": {}}]`},
+		{"append to root", `[{"op": "add", "path": "/-", "value": 1}]`},
+		{"root array index", `[{"op": "remove", "path": "/0"}]`},
+		{"invalid pointer", `[{"op": "remove", "path": "a"}]`},
+		{"missing value", `[{"op": "add", "path": "/a"}]`},
+		{"malformed", `{"op": "add"}`},
+	} {
+		tc := tc
+		t.Run("rejects "+tc.desc, func(t *testing.T) {
+			withSuite(t, func(s *Suite) {
+				_, err := s.client.FromJSONPatch("123", []byte(tc.patch))
+				require.Error(t, err)
+			})
+
+			_, err := sanity.FromJSONPatch("123", []byte(tc.patch))
+			require.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,179 @@
+package sanity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sanity-io/client-go/api"
+)
+
+// WithDocumentLoader returns an option that makes GetDocument coalesce calls: IDs requested
+// within the given window of the first pending call are fetched together with a single
+// GetDocuments request. This cuts the number of requests when many parts of an application
+// fetch documents by ID at the same time, such as when resolving shared references.
+// Concurrent callers requesting the same ID receive the same document, which must therefore
+// not be modified.
+func WithDocumentLoader(window time.Duration) Option {
+	return func(c *Client) { c.loaderWindow = window }
+}
+
+// GetDocument returns the document with the given ID. If the document does not exist,
+// ErrDocumentNotFound is returned.
+// On API request failure, this will return an error of type *RequestError.
+func (c *Client) GetDocument(ctx context.Context, id string) (api.Document, error) {
+	if c.loader != nil {
+		return c.loader.load(ctx, id)
+	}
+
+	resp, err := c.GetDocuments(id).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, doc := range resp.Documents {
+		if doc["_id"] == id {
+			return doc, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrDocumentNotFound, id)
+}
+
+// GetDocumentInto returns the document with the given ID decoded into a value of type T,
+// which is usually a struct. The boolean is false, and the value is the zero value, if the
+// document does not exist.
+// On API request failure, this will return an error of type *RequestError.
+func GetDocumentInto[T any](ctx context.Context, c *Client, id string) (T, bool, error) {
+	var v T
+
+	doc, err := c.GetDocument(ctx, id)
+	if errors.Is(err, ErrDocumentNotFound) {
+		return v, false, nil
+	}
+	if err != nil {
+		return v, false, err
+	}
+
+	b, err := json.Marshal(doc)
+	if err != nil {
+		return v, false, fmt.Errorf("encoding document: %w", err)
+	}
+	if err := unmarshalJSON(b, &v, c.useNumber); err != nil {
+		return v, false, fmt.Errorf("decoding document: %w", err)
+	}
+	return v, true, nil
+}
+
+type loaderResult struct {
+	doc api.Document
+	err error
+}
+
+// loaderWaiter is a GetDocument call waiting for a batch.
+type loaderWaiter struct {
+	ctx context.Context
+	ch  chan loaderResult
+}
+
+// documentLoader batches GetDocument calls.
+type documentLoader struct {
+	c       *Client
+	window  time.Duration
+	mu      sync.Mutex
+	pending map[string][]loaderWaiter
+}
+
+func newDocumentLoader(c *Client, window time.Duration) *documentLoader {
+	return &documentLoader{c: c, window: window}
+}
+
+func (l *documentLoader) load(ctx context.Context, id string) (api.Document, error) {
+	ch := make(chan loaderResult, 1)
+
+	l.mu.Lock()
+	if l.pending == nil {
+		l.pending = map[string][]loaderWaiter{}
+		time.AfterFunc(l.window, l.flush)
+	}
+	l.pending[id] = append(l.pending[id], loaderWaiter{ctx: ctx, ch: ch})
+	l.mu.Unlock()
+
+	select {
+	case res := <-ch:
+		return res.doc, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// flush fetches all pending IDs with a single GetDocuments call, which falls back to a POST
+// request if there are too many to fit in the URL. The request serves all waiting callers,
+// so it is cancelled only once the contexts of all of them are done.
+func (l *documentLoader) flush() {
+	l.mu.Lock()
+	pending := l.pending
+	l.pending = nil
+	l.mu.Unlock()
+
+	ids := make([]string, 0, len(pending))
+	var ctxs []context.Context
+	for id, waiters := range pending {
+		ids = append(ids, id)
+		for _, w := range waiters {
+			ctxs = append(ctxs, w.ctx)
+		}
+	}
+
+	ctx, cancel := allDone(ctxs)
+	defer cancel()
+
+	resp, err := l.c.GetDocuments(ids...).Do(ctx)
+
+	docs := map[string]api.Document{}
+	if err == nil {
+		for _, doc := range resp.Documents {
+			if id, ok := doc["_id"].(string); ok {
+				docs[id] = doc
+			}
+		}
+	}
+
+	for _, id := range ids {
+		res := loaderResult{err: err}
+		if err == nil {
+			if doc, ok := docs[id]; ok {
+				res.doc = doc
+			} else {
+				res.err = fmt.Errorf("%w: %q", ErrDocumentNotFound, id)
+			}
+		}
+		for _, w := range pending[id] {
+			w.ch <- res
+		}
+	}
+}
+
+// allDone returns a context that is cancelled once all of the given contexts are done.
+func allDone(ctxs []context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	remaining := int32(len(ctxs))
+	stops := make([]func() bool, len(ctxs))
+	for i, c := range ctxs {
+		stops[i] = context.AfterFunc(c, func() {
+			if atomic.AddInt32(&remaining, -1) == 0 {
+				cancel()
+			}
+		})
+	}
+
+	return ctx, func() {
+		for _, stop := range stops {
+			stop()
+		}
+		cancel()
+	}
+}
@@ -0,0 +1,233 @@
+package sanity_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sanity "github.com/sanity-io/client-go"
+	"github.com/sanity-io/client-go/api"
+)
+
+func TestGetDocument(t *testing.T) {
+	serve := func(s *Suite, requests *[][]string, mu *sync.Mutex) {
+		s.mux.Get("/v1/data/doc/myDataset/{ids}", func(w http.ResponseWriter, r *http.Request) {
+			ids := strings.Split(chi.URLParam(r, "ids"), ",")
+			sort.Strings(ids)
+			mu.Lock()
+			*requests = append(*requests, ids)
+			mu.Unlock()
+
+			docs := []api.Document{}
+			for _, id := range ids {
+				if id != "missing" {
+					docs = append(docs, api.Document{"_id": id})
+				}
+			}
+			_, err := w.Write(mustJSONBytes(&api.GetDocumentsResponse{Documents: docs}))
+			assert.NoError(t, err)
+		})
+	}
+
+	t.Run("without loader", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			var requests [][]string
+			var mu sync.Mutex
+			serve(s, &requests, &mu)
+
+			doc, err := s.client.GetDocument(context.Background(), "doc1")
+			require.NoError(t, err)
+			assert.Equal(t, "doc1", doc["_id"])
+
+			_, err = s.client.GetDocument(context.Background(), "missing")
+			assert.True(t, errors.Is(err, sanity.ErrDocumentNotFound))
+			assert.Len(t, requests, 2)
+		})
+	})
+
+	t.Run("coalesces concurrent calls", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			var requests [][]string
+			var mu sync.Mutex
+			serve(s, &requests, &mu)
+
+			ids := []string{"a", "b", "c", "a", "missing"}
+			errs := make([]error, len(ids))
+			docs := make([]api.Document, len(ids))
+
+			var wg sync.WaitGroup
+			for i, id := range ids {
+				wg.Add(1)
+				go func(i int, id string) {
+					defer wg.Done()
+					docs[i], errs[i] = s.client.GetDocument(context.Background(), id)
+				}(i, id)
+			}
+			wg.Wait()
+
+			require.Len(t, requests, 1)
+			assert.Equal(t, []string{"a", "b", "c", "missing"}, requests[0])
+
+			for i, id := range ids {
+				if id == "missing" {
+					assert.True(t, errors.Is(errs[i], sanity.ErrDocumentNotFound))
+					continue
+				}
+				require.NoError(t, errs[i])
+				assert.Equal(t, id, docs[i]["_id"])
+			}
+		}, sanity.WithDocumentLoader(50*time.Millisecond))
+	})
+
+	t.Run("fetches long batches with one query", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			var mu sync.Mutex
+			var requested []int
+			s.mux.Post("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				var req api.QueryRequest
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+				var ids []string
+				require.NoError(t, json.Unmarshal(*req.Params["ids"], &ids))
+				mu.Lock()
+				requested = append(requested, len(ids))
+				mu.Unlock()
+
+				docs := make([]api.Document, len(ids))
+				for i, id := range ids {
+					docs[i] = api.Document{"_id": id}
+				}
+				_, err := w.Write(mustJSONBytes(&api.QueryResponse{Result: mustJSONMsg(docs)}))
+				assert.NoError(t, err)
+			})
+
+			var wg sync.WaitGroup
+			for i := 0; i < 100; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					_, err := s.client.GetDocument(context.Background(), fmt.Sprintf("document-%03d", i))
+					assert.NoError(t, err)
+				}(i)
+			}
+			wg.Wait()
+
+			assert.Equal(t, []int{100}, requested)
+		}, sanity.WithDocumentLoader(50*time.Millisecond))
+	})
+
+	t.Run("cancels fetch once all callers give up", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			started, cancelled := make(chan struct{}), make(chan struct{})
+			s.mux.Get("/v1/data/doc/myDataset/{ids}", func(w http.ResponseWriter, r *http.Request) {
+				close(started)
+				<-r.Context().Done()
+				close(cancelled)
+			})
+
+			ctx1, cancel1 := context.WithCancel(context.Background())
+			ctx2, cancel2 := context.WithCancel(context.Background())
+			var wg sync.WaitGroup
+			for _, ctx := range []context.Context{ctx1, ctx2} {
+				wg.Add(1)
+				go func(ctx context.Context) {
+					defer wg.Done()
+					_, err := s.client.GetDocument(ctx, "a")
+					assert.True(t, errors.Is(err, context.Canceled))
+				}(ctx)
+			}
+
+			<-started
+			cancel1()
+			select {
+			case <-cancelled:
+				t.Fatal("fetch cancelled while a caller is waiting")
+			case <-time.After(20 * time.Millisecond):
+			}
+
+			cancel2()
+			select {
+			case <-cancelled:
+			case <-time.After(time.Second):
+				t.Fatal("fetch not cancelled")
+			}
+			wg.Wait()
+		}, sanity.WithDocumentLoader(10*time.Millisecond))
+	})
+
+	t.Run("caller context", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			var requests [][]string
+			var mu sync.Mutex
+			serve(s, &requests, &mu)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			_, err := s.client.GetDocument(ctx, "a")
+			assert.True(t, errors.Is(err, context.Canceled))
+		}, sanity.WithDocumentLoader(10*time.Millisecond))
+	})
+}
+
+func TestGetDocumentInto(t *testing.T) {
+	now := time.Date(2020, 1, 2, 23, 01, 44, 0, time.UTC)
+	testDoc := testDocument{ID: "doc1", Type: "doc", CreatedAt: now, UpdatedAt: now, Value: "hello"}
+
+	serve := func(s *Suite) {
+		s.mux.Get("/v1/data/doc/myDataset/{id}", func(w http.ResponseWriter, r *http.Request) {
+			docs := []api.Document{}
+			if chi.URLParam(r, "id") == testDoc.ID {
+				docs = append(docs, testDoc.toMap())
+			}
+			_, err := w.Write(mustJSONBytes(&api.GetDocumentsResponse{Documents: docs}))
+			assert.NoError(t, err)
+		})
+	}
+
+	t.Run("found", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			serve(s)
+
+			doc, found, err := sanity.GetDocumentInto[testDocument](context.Background(), s.client, "doc1")
+			require.NoError(t, err)
+			assert.True(t, found)
+			assert.Equal(t, testDoc, doc)
+		})
+	})
+
+	t.Run("not found", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			serve(s)
+
+			doc, found, err := sanity.GetDocumentInto[testDocument](context.Background(), s.client, "missing")
+			require.NoError(t, err)
+			assert.False(t, found)
+			assert.Equal(t, testDocument{}, doc)
+		})
+	})
+
+	t.Run("request error", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/doc/myDataset/{id}", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusForbidden)
+			})
+
+			_, found, err := sanity.GetDocumentInto[testDocument](context.Background(), s.client, "doc1")
+			require.Error(t, err)
+			assert.False(t, found)
+
+			var reqErr *sanity.RequestError
+			assert.True(t, errors.As(err, &reqErr))
+		})
+	})
+}
@@ -0,0 +1,319 @@
+package sanity
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/sanity-io/client-go/api"
+)
+
+// MigrationPlan holds the mutations that make a target dataset match a source dataset.
+type MigrationPlan struct {
+	// Creates holds create mutations for documents that only exist in the source.
+	Creates []*api.MutationItem
+
+	// Updates holds createOrReplace mutations for documents that differ between the source
+	// and the target.
+	Updates []*api.MutationItem
+
+	// Deletes holds delete mutations for documents that only exist in the target.
+	Deletes []*api.MutationItem
+}
+
+// Mutations returns all mutations of the plan, with creates first and deletes last.
+func (p *MigrationPlan) Mutations() []*api.MutationItem {
+	items := make([]*api.MutationItem, 0, len(p.Creates)+len(p.Updates)+len(p.Deletes))
+	items = append(items, p.Creates...)
+	items = append(items, p.Updates...)
+	return append(items, p.Deletes...)
+}
+
+// Empty returns true if the plan has no mutations, meaning that the datasets already match.
+func (p *MigrationPlan) Empty() bool {
+	return len(p.Creates)+len(p.Updates)+len(p.Deletes) == 0
+}
+
+// DiffOption is an option for DiffDatasets.
+type DiffOption func(o *diffOptions)
+
+type diffOptions struct {
+	types []string
+}
+
+// DiffTypes returns an option that limits the comparison to documents of the given types.
+// Documents of other types are neither updated nor deleted.
+func DiffTypes(types ...string) DiffOption {
+	return func(o *diffOptions) { o.types = append(o.types, types...) }
+}
+
+// systemFields are set by the server, and are ignored when comparing documents.
+var systemFields = []string{"_rev", "_createdAt", "_updatedAt"}
+
+// DiffDatasets compares the dataset of the client, the target, with the dataset of source,
+// and returns a plan of the mutations that make the target match the source. Both datasets
+// are streamed through the export API; only a hash of each target document is held in
+// memory, along with the planned mutations.
+//
+// Documents are compared by content, ignoring _rev, _createdAt and _updatedAt.
+// On API request failure, this will return an error of type *RequestError.
+func (c *Client) DiffDatasets(ctx context.Context, source *Client, opts ...DiffOption) (*MigrationPlan, error) {
+	var o diffOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	target := map[string][sha256.Size]byte{}
+	if err := c.Export().Types(o.types...).DoStream(ctx, func(doc api.Document) error {
+		id, sum, err := hashDocument(doc)
+		if err != nil {
+			return err
+		}
+		target[id] = sum
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("exporting target dataset: %w", err)
+	}
+
+	var plan MigrationPlan
+	seen := make(map[string]bool, len(target))
+	if err := source.Export().Types(o.types...).DoStream(ctx, func(doc api.Document) error {
+		id, sum, err := hashDocument(doc)
+		if err != nil {
+			return err
+		}
+		seen[id] = true
+
+		existing, ok := target[id]
+		if ok && existing == sum {
+			return nil
+		}
+
+		for _, field := range []string{"_rev", "_updatedAt"} {
+			delete(doc, field)
+		}
+		b, err := marshalJSON(doc)
+		if err != nil {
+			return fmt.Errorf("marshaling document %q: %w", id, err)
+		}
+
+		if ok {
+			plan.Updates = append(plan.Updates, &api.MutationItem{CreateOrReplace: b})
+		} else {
+			plan.Creates = append(plan.Creates, &api.MutationItem{Create: b})
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("exporting source dataset: %w", err)
+	}
+
+	var deleted []string
+	for id := range target {
+		if !seen[id] {
+			deleted = append(deleted, id)
+		}
+	}
+	sort.Strings(deleted)
+	for _, id := range deleted {
+		plan.Deletes = append(plan.Deletes, &api.MutationItem{Delete: &api.Delete{ID: id}})
+	}
+
+	return &plan, nil
+}
+
+// hashDocument returns the ID of the document and a hash of its content without system
+// fields. Map keys are sorted when marshaled, so equal documents have equal hashes.
+func hashDocument(doc api.Document) (string, [sha256.Size]byte, error) {
+	id, ok := doc["_id"].(string)
+	if !ok || id == "" {
+		return "", [sha256.Size]byte{}, fmt.Errorf("document has no _id")
+	}
+
+	content := make(map[string]interface{}, len(doc))
+	for k, v := range doc {
+		content[k] = v
+	}
+	for _, field := range systemFields {
+		delete(content, field)
+	}
+
+	b, err := json.Marshal(content)
+	if err != nil {
+		return "", [sha256.Size]byte{}, fmt.Errorf("marshaling document %q: %w", id, err)
+	}
+	return id, sha256.Sum256(b), nil
+}
+
+// MigrationResult is the result of applying a migration plan.
+type MigrationResult struct {
+	// TransactionIDs holds the ID of each transaction that was committed, in order. It is
+	// empty for a dry run.
+	TransactionIDs []string
+
+	// Applied is the number of mutations that were applied, or that would have been applied
+	// for a dry run.
+	Applied int
+
+	// Rollback is a plan that restores the documents to the state they were in before the
+	// migration was applied. Documents that did not exist are deleted, and all others are
+	// restored with createOrReplace.
+	Rollback *MigrationPlan
+}
+
+// ApplyOption is an option for ApplyMigrationPlan.
+type ApplyOption func(o *applyOptions)
+
+type applyOptions struct {
+	dryRun    bool
+	batchSize int
+}
+
+// ApplyDryRun returns an option that validates the mutations with the API without applying
+// them. The result still holds the rollback plan for the current state of the documents.
+func ApplyDryRun(enable bool) ApplyOption {
+	return func(o *applyOptions) { o.dryRun = enable }
+}
+
+// ApplyBatchSize returns an option that sets the maximum number of mutations per
+// transaction. The default is 100.
+func ApplyBatchSize(n int) ApplyOption {
+	return func(o *applyOptions) { o.batchSize = n }
+}
+
+// ApplyMigrationPlan applies the mutations of the plan to the dataset of the client, in
+// transactions of a limited number of mutations. Before each transaction, the current state
+// of the affected documents is recorded, and the result holds a rollback plan built from it.
+//
+// Each transaction is atomic, but the plan as a whole is not: if a transaction fails, the
+// transactions committed before it remain applied, and the returned result covers them, so
+// that they can be rolled back.
+// On API request failure, this will return an error of type *RequestError.
+func (c *Client) ApplyMigrationPlan(ctx context.Context, plan *MigrationPlan, opts ...ApplyOption) (*MigrationResult, error) {
+	o := applyOptions{batchSize: 100}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.batchSize <= 0 {
+		return nil, fmt.Errorf("batch size must be positive")
+	}
+
+	items := plan.Mutations()
+	result := &MigrationResult{Rollback: &MigrationPlan{}}
+	recorded := map[string]bool{}
+	for start := 0; start < len(items); start += o.batchSize {
+		end := start + o.batchSize
+		if end > len(items) {
+			end = len(items)
+		}
+		batch := items[start:end]
+
+		ids := make([]string, len(batch))
+		for i, item := range batch {
+			id, err := mutationDocumentID(item)
+			if err != nil {
+				return result, err
+			}
+			ids[i] = id
+		}
+
+		prior, err := c.fetchDocumentsByID(ctx, ids)
+		if err != nil {
+			return result, fmt.Errorf("fetching current documents: %w", err)
+		}
+
+		mb := c.Mutate().ReturnDocuments(false).DryRun(o.dryRun)
+		mb.items = batch
+		resp, err := mb.Do(ctx)
+		if err != nil {
+			return result, err
+		}
+
+		if !o.dryRun {
+			result.TransactionIDs = append(result.TransactionIDs, resp.TransactionID)
+		}
+		result.Applied += len(batch)
+		if err := result.Rollback.addRollback(ids, prior, recorded); err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// addRollback adds the mutations that restore the documents with the given IDs to their
+// prior state. Documents that are already recorded are skipped, since their earliest state
+// is the one to restore.
+func (p *MigrationPlan) addRollback(ids []string, prior map[string]api.Document, recorded map[string]bool) error {
+	for _, id := range ids {
+		if recorded[id] {
+			continue
+		}
+		recorded[id] = true
+
+		doc, existed := prior[id]
+		if !existed {
+			p.Deletes = append(p.Deletes, &api.MutationItem{Delete: &api.Delete{ID: id}})
+			continue
+		}
+
+		for _, field := range []string{"_rev", "_updatedAt"} {
+			delete(doc, field)
+		}
+		b, err := marshalJSON(doc)
+		if err != nil {
+			return fmt.Errorf("marshaling document %q: %w", id, err)
+		}
+
+		p.Updates = append(p.Updates, &api.MutationItem{CreateOrReplace: b})
+	}
+	return nil
+}
+
+// fetchDocumentsByID returns the documents with the given IDs that exist, by ID, as stored.
+func (c *Client) fetchDocumentsByID(ctx context.Context, ids []string) (map[string]api.Document, error) {
+	raws, err := c.GetDocuments(ids...).queryDocuments(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]api.Document, len(raws))
+	for _, raw := range raws {
+		var doc api.Document
+		if err := unmarshalJSON(raw, &doc, c.useNumber); err != nil {
+			return nil, fmt.Errorf("decoding document: %w", err)
+		}
+		if id, ok := doc["_id"].(string); ok {
+			byID[id] = doc
+		}
+	}
+	return byID, nil
+}
+
+// mutationDocumentID returns the ID of the single document affected by a mutation.
+func mutationDocumentID(item *api.MutationItem) (string, error) {
+	var doc *json.RawMessage
+	switch {
+	case item.Create != nil:
+		doc = item.Create
+	case item.CreateIfNotExists != nil:
+		doc = item.CreateIfNotExists
+	case item.CreateOrReplace != nil:
+		doc = item.CreateOrReplace
+	case item.Delete != nil && item.Delete.ID != "":
+		return item.Delete.ID, nil
+	case item.Patch != nil && item.Patch.ID != "":
+		return item.Patch.ID, nil
+	default:
+		return "", fmt.Errorf("mutation does not target a single document by ID")
+	}
+
+	var v struct {
+		ID string `json:"_id"`
+	}
+	if err := json.Unmarshal(*doc, &v); err != nil || v.ID == "" {
+		return "", fmt.Errorf("mutation document has no _id")
+	}
+	return v.ID, nil
+}
@@ -0,0 +1,195 @@
+package sanity_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sanity "github.com/sanity-io/client-go"
+	"github.com/sanity-io/client-go/api"
+)
+
+func serveExport(t *testing.T, s *Suite, docs ...string) {
+	s.mux.Get("/v1/data/export/myDataset", func(w http.ResponseWriter, r *http.Request) {
+		types := r.URL.Query().Get("types")
+		for _, doc := range docs {
+			if types != "" && !strings.Contains(doc, `"_type":"`+types+`"`) {
+				continue
+			}
+			_, err := w.Write([]byte(doc + "\n"))
+			assert.NoError(t, err)
+		}
+	})
+}
+
+func mutationJSON(t *testing.T, v interface{}) string {
+	b, err := json.Marshal(v)
+	require.NoError(t, err)
+	return string(b)
+}
+
+func TestDiffDatasets(t *testing.T) {
+	withSuite(t, func(target *Suite) {
+		withSuite(t, func(source *Suite) {
+			serveExport(t, target,
+				`{"_id":"same","_type":"post","_rev":"r1","title":"Same"}`,
+				`{"_id":"changed","_type":"post","_rev":"r1","title":"Old"}`,
+				`{"_id":"removed","_type":"post","_rev":"r1"}`,
+				`{"_id":"other","_type":"author","_rev":"r1"}`,
+			)
+			serveExport(t, source,
+				`{"_id":"same","_type":"post","_rev":"r9","_updatedAt":"2021-01-01T00:00:00Z","title":"Same"}`,
+				`{"_id":"changed","_type":"post","_rev":"r9","title":"New"}`,
+				`{"_id":"added","_type":"post","_rev":"r9"}`,
+			)
+
+			t.Run("computes creates, updates and deletes", func(t *testing.T) {
+				plan, err := target.client.DiffDatasets(context.Background(), source.client)
+				require.NoError(t, err)
+
+				assert.Equal(t, `[{"create":{"_id":"added","_type":"post"}}]`, mutationJSON(t, plan.Creates))
+				assert.Equal(t, `[{"createOrReplace":{"_id":"changed","_type":"post","title":"New"}}]`, mutationJSON(t, plan.Updates))
+				assert.Equal(t, `[{"delete":{"id":"other"}},{"delete":{"id":"removed"}}]`, mutationJSON(t, plan.Deletes))
+				assert.Len(t, plan.Mutations(), 4)
+				assert.False(t, plan.Empty())
+			})
+
+			t.Run("filters by type", func(t *testing.T) {
+				plan, err := target.client.DiffDatasets(context.Background(), source.client, sanity.DiffTypes("author"))
+				require.NoError(t, err)
+
+				assert.Empty(t, plan.Creates)
+				assert.Empty(t, plan.Updates)
+				assert.Equal(t, `[{"delete":{"id":"other"}}]`, mutationJSON(t, plan.Deletes))
+			})
+
+			t.Run("empty when datasets match", func(t *testing.T) {
+				plan, err := target.client.DiffDatasets(context.Background(), target.client)
+				require.NoError(t, err)
+				assert.True(t, plan.Empty())
+			})
+		})
+	})
+}
+
+func TestApplyMigrationPlan(t *testing.T) {
+	plan := &sanity.MigrationPlan{
+		Creates: []*api.MutationItem{{Create: mustJSONMsg(map[string]string{"_id": "added", "_type": "post"})}},
+		Updates: []*api.MutationItem{{CreateOrReplace: mustJSONMsg(map[string]string{"_id": "changed", "_type": "post", "title": "New"})}},
+		Deletes: []*api.MutationItem{{Delete: &api.Delete{ID: "removed"}}},
+	}
+	current := map[string]api.Document{
+		"changed": {"_id": "changed", "_type": "post", "_rev": "r1", "title": "Old"},
+		"removed": {"_id": "removed", "_type": "post", "_rev": "r1"},
+	}
+
+	serve := func(t *testing.T, s *Suite, mutations *[]string, dryRuns *[]string) {
+		s.mux.Post("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+			// Like the API, leave out drafts with the published perspective.
+			published := r.URL.Query().Get("perspective") == "published"
+
+			var req api.QueryRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			var ids []string
+			require.NoError(t, json.Unmarshal(*req.Params["ids"], &ids))
+
+			docs := []api.Document{}
+			for _, id := range ids {
+				if published && strings.HasPrefix(id, "drafts.") {
+					continue
+				}
+				if doc, ok := current[id]; ok {
+					docs = append(docs, doc)
+				}
+			}
+			_, err := w.Write(mustJSONBytes(&api.QueryResponse{Result: mustJSONMsg(docs)}))
+			assert.NoError(t, err)
+		})
+		s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+			var req struct {
+				Mutations json.RawMessage `json:"mutations"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			*mutations = append(*mutations, string(req.Mutations))
+			*dryRuns = append(*dryRuns, r.URL.Query().Get("dryRun"))
+
+			_, err := w.Write(mustJSONBytes(&api.MutateResponse{TransactionID: "tx" + strconv.Itoa(len(*mutations))}))
+			assert.NoError(t, err)
+		})
+	}
+
+	t.Run("applies in batches and records rollback", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			var mutations, dryRuns []string
+			serve(t, s, &mutations, &dryRuns)
+
+			result, err := s.client.ApplyMigrationPlan(context.Background(), plan, sanity.ApplyBatchSize(2))
+			require.NoError(t, err)
+
+			assert.Equal(t, []string{
+				`[{"create":{"_id":"added","_type":"post"}},{"createOrReplace":{"_id":"changed","_type":"post","title":"New"}}]`,
+				`[{"delete":{"id":"removed"}}]`,
+			}, mutations)
+			assert.Equal(t, []string{"false", "false"}, dryRuns)
+			assert.Equal(t, []string{"tx1", "tx2"}, result.TransactionIDs)
+			assert.Equal(t, 3, result.Applied)
+
+			assert.Empty(t, result.Rollback.Creates)
+			assert.Equal(t,
+				`[{"createOrReplace":{"_id":"changed","_type":"post","title":"Old"}},{"createOrReplace":{"_id":"removed","_type":"post"}}]`,
+				mutationJSON(t, result.Rollback.Updates))
+			assert.Equal(t, `[{"delete":{"id":"added"}}]`, mutationJSON(t, result.Rollback.Deletes))
+		})
+	})
+
+	t.Run("records drafts regardless of perspective", func(t *testing.T) {
+		plan := &sanity.MigrationPlan{
+			Updates: []*api.MutationItem{{CreateOrReplace: mustJSONMsg(map[string]string{"_id": "drafts.changed", "_type": "post"})}},
+		}
+		current["drafts.changed"] = api.Document{"_id": "drafts.changed", "_type": "post", "_rev": "r1", "title": "Draft"}
+		defer delete(current, "drafts.changed")
+
+		withSuite(t, func(s *Suite) {
+			var mutations, dryRuns []string
+			serve(t, s, &mutations, &dryRuns)
+
+			result, err := s.client.ApplyMigrationPlan(context.Background(), plan)
+			require.NoError(t, err)
+
+			assert.Empty(t, result.Rollback.Deletes)
+			assert.Equal(t,
+				`[{"createOrReplace":{"_id":"drafts.changed","_type":"post","title":"Draft"}}]`,
+				mutationJSON(t, result.Rollback.Updates))
+		}, sanity.WithPerspective("published"))
+	})
+
+	t.Run("dry run", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			var mutations, dryRuns []string
+			serve(t, s, &mutations, &dryRuns)
+
+			result, err := s.client.ApplyMigrationPlan(context.Background(), plan, sanity.ApplyDryRun(true))
+			require.NoError(t, err)
+
+			assert.Equal(t, []string{"true"}, dryRuns)
+			assert.Empty(t, result.TransactionIDs)
+			assert.Equal(t, 3, result.Applied)
+			assert.Len(t, result.Rollback.Mutations(), 3)
+		})
+	})
+
+	t.Run("rejects mutations without document ID", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			_, err := s.client.ApplyMigrationPlan(context.Background(), &sanity.MigrationPlan{
+				Updates: []*api.MutationItem{{Patch: &api.Patch{Query: "*"}}},
+			})
+			require.Error(t, err)
+		})
+	})
+}
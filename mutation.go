@@ -21,6 +21,31 @@ func (c *Client) Mutate() *MutationBuilder {
 type MutateResult struct {
 	TransactionID string
 	Results       []*api.MutateResultItem
+
+	// InvalidatedTags holds the cache tags set with MutationBuilder.InvalidateTags.
+	InvalidatedTags []string
+}
+
+// UnmarshalDocuments unmarshals the returned documents into dest, which must be a pointer
+// to a slice. Elements are in the same order as Results; an element is left as the zero
+// value if its result has no document, such as when ReturnDocuments is disabled.
+func (r *MutateResult) UnmarshalDocuments(dest interface{}) error {
+	docs := make([]*json.RawMessage, len(r.Results))
+	for i, item := range r.Results {
+		if item != nil {
+			docs[i] = item.Document
+		}
+	}
+
+	b, err := json.Marshal(docs)
+	if err != nil {
+		return fmt.Errorf("marshaling documents: %w", err)
+	}
+
+	if err := json.Unmarshal(b, dest); err != nil {
+		return fmt.Errorf("unmarshaling documents: %w", err)
+	}
+	return nil
 }
 
 type MutationBuilder struct {
@@ -33,6 +58,15 @@ type MutationBuilder struct {
 	transactionID string
 	dryRun        bool
 	tag           string
+	invalidate    []string
+	lean          bool
+	autoKeys      bool
+	conditions    []string
+	condParams    []QueryParam
+	dataset       string // overrides the client dataset if set
+	drafts        bool
+	releaseID     string
+	skipCrossRefs bool
 }
 
 func (mb *MutationBuilder) Visibility(v api.MutationVisibility) *MutationBuilder {
@@ -60,42 +94,194 @@ func (mb *MutationBuilder) DryRun(enable bool) *MutationBuilder {
 	return mb
 }
 
+// OmitDefaultParams makes the request leave out the returnIds, returnDocuments, visibility
+// and dryRun parameters when they are set to the API's defaults (false, false, sync and
+// false), rather than always sending them. The API treats an omitted parameter the same as
+// its default, so this only makes the request smaller. Note that ReturnDocuments defaults to
+// true in this builder, unlike in the API, so it is still sent unless disabled.
+func (mb *MutationBuilder) OmitDefaultParams(enable bool) *MutationBuilder {
+	mb.lean = enable
+	return mb
+}
+
+// AutoGenerateArrayKeys makes the API add a random _key to every object in an array that
+// lacks one, in all documents of the mutation. Unlike PatchBuilder.WithAutoKeys, which adds
+// keys to inserted items in the client, this covers whole documents without walking them,
+// which is convenient for imports.
+func (mb *MutationBuilder) AutoGenerateArrayKeys(enable bool) *MutationBuilder {
+	mb.autoKeys = enable
+	return mb
+}
+
+// TargetDrafts makes the mutations target the drafts of the documents rather than the
+// published documents, by prefixing the IDs of creates, deletes and patches with "drafts.".
+// IDs that already have the prefix are left as they are, as are mutations by query and raw
+// mutations. A document to create without an _id gets a random draft ID. This makes it
+// possible to switch a tool between editing published and draft content without rewriting
+// every ID.
+func (mb *MutationBuilder) TargetDrafts(enable bool) *MutationBuilder {
+	mb.drafts = enable
+	return mb
+}
+
+// ReleaseID makes the mutation target the content release with the given ID, rather than
+// the current content of the documents.
+func (mb *MutationBuilder) ReleaseID(id string) *MutationBuilder {
+	mb.releaseID = id
+	return mb
+}
+
+// SkipCrossDatasetReferenceValidation makes the API skip checking that cross-dataset
+// references in the mutated documents point to existing documents.
+func (mb *MutationBuilder) SkipCrossDatasetReferenceValidation(enable bool) *MutationBuilder {
+	mb.skipCrossRefs = enable
+	return mb
+}
+
+// Tag sets the request tag, overriding the client default set with WithTag.
 func (mb *MutationBuilder) Tag(val string) *MutationBuilder {
 	mb.tag = val
 	return mb
 }
 
+// InvalidateTags records cache tags that are affected by the mutation. After the mutation
+// succeeds, the tags are passed to the OnInvalidateTags callback and returned in
+// MutateResult.InvalidatedTags, so that the caller can purge its own caches.
+//
+// The API does not offer a way to purge the Sanity API CDN: it invalidates cached query
+// results by itself after a mutation, but this is not instantaneous, so reads through the
+// CDN may be stale for a short while after a write.
+func (mb *MutationBuilder) InvalidateTags(tags ...string) *MutationBuilder {
+	mb.invalidate = append(mb.invalidate, tags...)
+	return mb
+}
+
 func (mb *MutationBuilder) Do(ctx context.Context) (*MutateResult, error) {
+	result, _, err := mb.do(ctx, false)
+	return result, err
+}
+
+// DoWithResponse is like Do, but also returns the HTTP response, such as for reading the
+// rate limit headers. The response body has already been read, but is preserved, so it can
+// be read again.
+func (mb *MutationBuilder) DoWithResponse(ctx context.Context) (*MutateResult, *http.Response, error) {
+	return mb.do(ctx, true)
+}
+
+func (mb *MutationBuilder) do(ctx context.Context, preserve bool) (*MutateResult, *http.Response, error) {
 	if mb.err != nil {
-		return nil, fmt.Errorf("mutation builder: %w", mb.err)
+		return nil, nil, fmt.Errorf("mutation builder: %w", mb.err)
+	}
+	if err := mb.validate(); err != nil {
+		return nil, nil, fmt.Errorf("mutation builder: %w", err)
 	}
 
-	req := mb.c.newAPIRequest().
+	items := mb.items
+	if mb.drafts {
+		var err error
+		if items, err = draftItems(items); err != nil {
+			return nil, nil, fmt.Errorf("mutation builder: %w", err)
+		}
+	}
+	if len(mb.conditions) > 0 {
+		var err error
+		if items, err = mb.checkConditions(ctx, items); err != nil {
+			return nil, nil, fmt.Errorf("mutate: %w", err)
+		}
+	}
+
+	req := mb.c.forDataset(mb.c.newAPIRequest(), mb.targetDataset()).
 		Method(http.MethodPost).
-		AppendPath("data/mutate", mb.c.dataset).
-		Param("returnIds", mb.returnIDs).
-		Param("returnDocuments", mb.returnDocs).
-		Param("visibility", string(mb.visibility)).
-		Param("dryRun", mb.dryRun).
-		MarshalBody(&api.MutateRequest{Mutations: mb.items}).
+		AppendPath("data/mutate", mb.targetDataset()).
+		MarshalBody(&api.MutateRequest{Mutations: items}).
 		Tag(mb.tag, mb.c.tag)
-	if mb.transactionID != "" {
-		req.Param("transactionId", mb.transactionID)
+	if mb.returnIDs || !mb.lean {
+		req.Param("returnIds", mb.returnIDs)
+	}
+	if mb.returnDocs || !mb.lean {
+		req.Param("returnDocuments", mb.returnDocs)
+	}
+	if mb.visibility != api.MutationVisibilitySync || !mb.lean {
+		req.Param("visibility", string(mb.visibility))
+	}
+	if mb.dryRun || !mb.lean {
+		req.Param("dryRun", mb.dryRun)
+	}
+	if mb.autoKeys {
+		req.Param("autoGenerateArrayKeys", true)
+	}
+	if mb.releaseID != "" {
+		req.Param("releaseId", mb.releaseID)
+	}
+	if mb.skipCrossRefs {
+		req.Param("skipCrossDatasetReferenceValidation", true)
+	}
+
+	transactionID := mb.transactionID
+	if transactionID == "" && mb.c.retryMutation {
+		id, err := generateID()
+		if err != nil {
+			return nil, nil, fmt.Errorf("mutate: %w", err)
+		}
+		transactionID = id
+	}
+	if transactionID != "" {
+		req.Param("transactionId", transactionID)
+	}
+	if mb.c.retryMutation {
+		req.Idempotent(true)
 	}
 
 	var resp api.MutateResponse
-	if _, err := mb.c.do(ctx, req, &resp); err != nil {
-		return nil, fmt.Errorf("mutate: %w", err)
+	var httpResp *http.Response
+	var err error
+	if preserve {
+		httpResp, err = mb.c.doPreserved(ctx, req, &resp)
+	} else {
+		httpResp, err = mb.c.do(ctx, req, &resp)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("mutate: %w", err)
+	}
+
+	if len(mb.invalidate) > 0 && !mb.dryRun && mb.c.callbacks.OnInvalidateTags != nil {
+		mb.c.callbacks.OnInvalidateTags(mb.invalidate)
 	}
 
 	return &MutateResult{
-		TransactionID: resp.TransactionID,
-		Results:       resp.Results,
-	}, nil
+		TransactionID:   resp.TransactionID,
+		Results:         resp.Results,
+		InvalidatedTags: mb.invalidate,
+	}, httpResp, nil
+}
+
+// Len returns the number of mutations added to the builder.
+func (mb *MutationBuilder) Len() int {
+	return len(mb.items)
+}
+
+// Reset removes all mutations, conditions set with If and tags set with InvalidateTags from
+// the builder, along with any error from building them, so that the builder can be reused,
+// such as in a loop after a successful Do. Other settings, such as the visibility and tag,
+// are kept.
+func (mb *MutationBuilder) Reset() *MutationBuilder {
+	mb.items = nil
+	mb.err = nil
+	mb.conditions = nil
+	mb.condParams = nil
+	mb.invalidate = nil
+	return mb
+}
+
+func (mb *MutationBuilder) targetDataset() string {
+	if mb.dataset != "" {
+		return mb.dataset
+	}
+	return mb.c.dataset
 }
 
 func (mb *MutationBuilder) Create(doc interface{}) *MutationBuilder {
-	b, ok := mb.marshalJSON(doc)
+	b, ok := mb.marshalDocument(doc)
 	if ok {
 		mb.items = append(mb.items, &api.MutationItem{Create: b})
 	}
@@ -111,7 +297,7 @@ func (mb *MutationBuilder) CreateIfNotExists(doc interface{}) *MutationBuilder {
 }
 
 func (mb *MutationBuilder) CreateOrReplace(doc interface{}) *MutationBuilder {
-	b, ok := mb.marshalJSON(doc)
+	b, ok := mb.marshalDocument(doc)
 	if ok {
 		mb.items = append(mb.items, &api.MutationItem{CreateOrReplace: b})
 	}
@@ -123,10 +309,82 @@ func (mb *MutationBuilder) Delete(id string) *MutationBuilder {
 	return mb
 }
 
+// DeleteByQuery deletes all documents matched by the query, such as *[_type == "draft"], in a
+// single mutation. Parameters referenced in the query as $name are given as params.
+func (mb *MutationBuilder) DeleteByQuery(query string, params ...QueryParam) *MutationBuilder {
+	del := &api.Delete{Query: query}
+	if len(params) > 0 {
+		del.Params = make(map[string]*json.RawMessage, len(params))
+		for _, p := range params {
+			b, err := json.Marshal(p.Value)
+			if err != nil {
+				mb.setErr(fmt.Errorf("marshaling parameter %q to JSON: %w", p.Name, err))
+				return mb
+			}
+			del.Params[p.Name] = (*json.RawMessage)(&b)
+		}
+	}
+	mb.items = append(mb.items, &api.MutationItem{Delete: del})
+	return mb
+}
+
+// Raw appends a pre-serialized mutation, such as {"patch":{...}}, which is sent verbatim.
+func (mb *MutationBuilder) Raw(item json.RawMessage) *MutationBuilder {
+	mb.items = append(mb.items, &api.MutationItem{Raw: item})
+	return mb
+}
+
 func (mb *MutationBuilder) Patch(id string) *PatchBuilder {
 	patch := &api.Patch{ID: id}
 	mb.items = append(mb.items, &api.MutationItem{Patch: patch})
-	return &PatchBuilder{mb, patch}
+	return &PatchBuilder{mb: mb, patch: patch}
+}
+
+// PatchByQuery returns a builder for a patch that applies to all documents matching the
+// query, rather than to a single document.
+func (mb *MutationBuilder) PatchByQuery(query string) *PatchBuilder {
+	patch := &api.Patch{Query: query}
+	mb.items = append(mb.items, &api.MutationItem{Patch: patch})
+	return &PatchBuilder{mb: mb, patch: patch}
+}
+
+// draftItems returns copies of the mutations that target the drafts of their documents.
+func draftItems(items []*api.MutationItem) ([]*api.MutationItem, error) {
+	drafts := make([]*api.MutationItem, len(items))
+	for i, item := range items {
+		draft := *item
+		for _, doc := range []**json.RawMessage{&draft.Create, &draft.CreateIfNotExists, &draft.CreateOrReplace} {
+			if *doc == nil {
+				continue
+			}
+			b, err := withDraftID(**doc)
+			if err != nil {
+				return nil, fmt.Errorf("mutation %d: %w", i, err)
+			}
+			*doc = (*json.RawMessage)(&b)
+		}
+		if item.Delete != nil && item.Delete.ID != "" {
+			del := *item.Delete
+			del.ID = draftID(del.ID)
+			draft.Delete = &del
+		}
+		if item.Patch != nil && item.Patch.ID != "" {
+			patch := *item.Patch
+			patch.ID = draftID(patch.ID)
+			draft.Patch = &patch
+		}
+		drafts[i] = &draft
+	}
+	return drafts, nil
+}
+
+func (mb *MutationBuilder) validate() error {
+	for i, item := range mb.items {
+		if item.Patch != nil && item.Patch.ID == "" && item.Patch.Query == "" {
+			return fmt.Errorf("patch %d must have a document ID or a query", i)
+		}
+	}
+	return nil
 }
 
 func (mb *MutationBuilder) setErr(err error) {
@@ -145,9 +403,34 @@ func (mb *MutationBuilder) marshalJSON(val interface{}) (*json.RawMessage, bool)
 	return b, true
 }
 
+// marshalDocument marshals a document to create, with an _id from the client's ID generator
+// if it lacks one.
+func (mb *MutationBuilder) marshalDocument(doc interface{}) (*json.RawMessage, bool) {
+	b, ok := mb.marshalJSON(doc)
+	if !ok || mb.c.idGenerator == nil {
+		return b, ok
+	}
+
+	withID, err := withDocumentID(*b, mb.c.idGenerator)
+	if err != nil {
+		mb.setErr(err)
+		return nil, false
+	}
+	return (*json.RawMessage)(&withID), true
+}
+
 type PatchBuilder struct {
-	mb    *MutationBuilder
-	patch *api.Patch
+	mb       *MutationBuilder
+	patch    *api.Patch
+	autoKeys bool
+}
+
+// WithAutoKeys makes subsequent inserts add a random _key to every inserted object that
+// lacks one. Sanity expects array objects to carry a unique _key, and Sanity Studio warns
+// about objects without one. The keys are generated once, when the items are added.
+func (pb *PatchBuilder) WithAutoKeys() *PatchBuilder {
+	pb.autoKeys = true
+	return pb
 }
 
 func (pb *PatchBuilder) IfRevisionID(id string) *PatchBuilder {
@@ -210,13 +493,9 @@ func (pb *PatchBuilder) Dec(path string, n float64) *PatchBuilder {
 }
 
 func (pb *PatchBuilder) InsertBefore(path string, items ...interface{}) *PatchBuilder {
-	bs := make([]*json.RawMessage, len(items))
-	for i, item := range items {
-		b, ok := pb.mb.marshalJSON(item)
-		if !ok {
-			return pb
-		}
-		bs[i] = b
+	bs, ok := pb.marshalItems(items)
+	if !ok {
+		return pb
 	}
 
 	pb.patch.Insert = &api.Insert{
@@ -227,13 +506,9 @@ func (pb *PatchBuilder) InsertBefore(path string, items ...interface{}) *PatchBu
 }
 
 func (pb *PatchBuilder) InsertAfter(path string, items ...interface{}) *PatchBuilder {
-	bs := make([]*json.RawMessage, len(items))
-	for i, item := range items {
-		b, ok := pb.mb.marshalJSON(item)
-		if !ok {
-			return pb
-		}
-		bs[i] = b
+	bs, ok := pb.marshalItems(items)
+	if !ok {
+		return pb
 	}
 
 	pb.patch.Insert = &api.Insert{
@@ -244,13 +519,9 @@ func (pb *PatchBuilder) InsertAfter(path string, items ...interface{}) *PatchBui
 }
 
 func (pb *PatchBuilder) InsertReplace(path string, items ...interface{}) *PatchBuilder {
-	bs := make([]*json.RawMessage, len(items))
-	for i, item := range items {
-		b, ok := pb.mb.marshalJSON(item)
-		if !ok {
-			return pb
-		}
-		bs[i] = b
+	bs, ok := pb.marshalItems(items)
+	if !ok {
+		return pb
 	}
 
 	pb.patch.Insert = &api.Insert{
@@ -260,6 +531,46 @@ func (pb *PatchBuilder) InsertReplace(path string, items ...interface{}) *PatchB
 	return pb
 }
 
+// Append inserts items at the end of the array at path. It is shorthand for
+// InsertAfter(path+"[-1]", items...).
+func (pb *PatchBuilder) Append(path string, items ...interface{}) *PatchBuilder {
+	return pb.InsertAfter(path+"[-1]", items...)
+}
+
+// Prepend inserts items at the start of the array at path. It is shorthand for
+// InsertBefore(path+"[0]", items...).
+func (pb *PatchBuilder) Prepend(path string, items ...interface{}) *PatchBuilder {
+	return pb.InsertBefore(path+"[0]", items...)
+}
+
+// Replace replaces all items of the array at path. It is shorthand for
+// InsertReplace(path+"[0:]", items...).
+func (pb *PatchBuilder) Replace(path string, items ...interface{}) *PatchBuilder {
+	return pb.InsertReplace(path+"[0:]", items...)
+}
+
+func (pb *PatchBuilder) marshalItems(items []interface{}) ([]*json.RawMessage, bool) {
+	bs := make([]*json.RawMessage, len(items))
+	for i, item := range items {
+		b, ok := pb.mb.marshalJSON(item)
+		if !ok {
+			return nil, false
+		}
+
+		if pb.autoKeys {
+			keyed, err := withArrayKey(*b)
+			if err != nil {
+				pb.mb.setErr(err)
+				return nil, false
+			}
+			b = (*json.RawMessage)(&keyed)
+		}
+
+		bs[i] = b
+	}
+	return bs, true
+}
+
 func (pb *PatchBuilder) End() *MutationBuilder {
 	return pb.mb
 }
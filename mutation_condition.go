@@ -0,0 +1,96 @@
+package sanity
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sanity-io/client-go/api"
+)
+
+// guardIDsParam is the query parameter holding the IDs of the patched documents whose
+// revisions are fetched along with the conditions.
+const guardIDsParam = "__guardIDs"
+
+// If adds a precondition to the mutation: a GROQ expression that must evaluate to true for
+// the mutation to be sent, such as *[_id == "post1"][0].status == "draft". If there are
+// several conditions, all of them must hold. Parameters referenced as $name are given as
+// params, and are shared by all conditions of the mutation. If a condition does not hold,
+// Do returns ErrPreconditionFailed without sending the mutation.
+//
+// The API has no conditional mutations, so the conditions are checked with a query before
+// the mutation is sent, which is not atomic. To make up for this, the same query fetches the
+// current revision of every document patched by ID, and each of these patches is guarded
+// with IfRevisionID, unless it already has one. If any of the documents changes between the
+// check and the mutation, the whole transaction is rejected by the API with a *RequestError
+// and nothing is applied. Creates, deletes and patches by query are not guarded, so a
+// condition on anything other than the patched documents may no longer hold when the
+// mutation is applied. Like the mutation itself, conditions see documents as stored, so a
+// condition on a draft must refer to it by its drafts. ID.
+func (mb *MutationBuilder) If(query string, params ...QueryParam) *MutationBuilder {
+	mb.conditions = append(mb.conditions, query)
+	mb.condParams = append(mb.condParams, params...)
+	return mb
+}
+
+// checkConditions evaluates the conditions, and returns the mutations to send, with their
+// patches guarded by the revisions read along with the conditions.
+func (mb *MutationBuilder) checkConditions(ctx context.Context, items []*api.MutationItem) ([]*api.MutationItem, error) {
+	var guardIDs []string
+	for _, item := range items {
+		if item.Patch != nil && item.Patch.ID != "" && item.Patch.IfRevisionID == "" {
+			guardIDs = append(guardIDs, item.Patch.ID)
+		}
+	}
+
+	conds := make([]string, len(mb.conditions))
+	for i, cond := range mb.conditions {
+		conds[i] = "(" + cond + ") == true"
+	}
+	query := fmt.Sprintf(`{"ok": %s, "revs": *[_id in $%s]{_id, _rev}}`,
+		strings.Join(conds, " && "), guardIDsParam)
+
+	qb := mb.c.storedQuery(query).withParams(mb.condParams).Param(guardIDsParam, guardIDs)
+	qb.dataset = mb.dataset
+	if mb.tag != "" {
+		qb.Tag(mb.tag)
+	}
+
+	result, err := qb.Do(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("checking precondition: %w", err)
+	}
+
+	var check struct {
+		OK   bool `json:"ok"`
+		Revs []struct {
+			ID  string `json:"_id"`
+			Rev string `json:"_rev"`
+		} `json:"revs"`
+	}
+	if err := result.Unmarshal(&check); err != nil {
+		return nil, fmt.Errorf("decoding precondition result: %w", err)
+	}
+	if !check.OK {
+		return nil, ErrPreconditionFailed
+	}
+
+	revs := make(map[string]string, len(check.Revs))
+	for _, r := range check.Revs {
+		revs[r.ID] = r.Rev
+	}
+
+	guarded := make([]*api.MutationItem, len(items))
+	for i, item := range items {
+		guarded[i] = item
+		if item.Patch == nil || item.Patch.IfRevisionID != "" {
+			continue
+		}
+		if rev, ok := revs[item.Patch.ID]; ok {
+			patch := *item.Patch
+			patch.IfRevisionID = rev
+			guarded[i] = &api.MutationItem{Patch: &patch}
+		}
+	}
+	return guarded, nil
+}
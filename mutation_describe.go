@@ -0,0 +1,111 @@
+package sanity
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/sanity-io/client-go/api"
+)
+
+// Describe returns a human-readable summary of the queued mutations, suitable for audit
+// logs. For example: "2 creates, 1 patch on doc1 (set title, inc views), 1 delete".
+func (mb *MutationBuilder) Describe() string {
+	var creates, createIfNotExists, createOrReplace, deletes, raw int
+	var patches []string
+	for _, item := range mb.items {
+		switch {
+		case item.Raw != nil:
+			raw++
+		case item.Create != nil:
+			creates++
+		case item.CreateIfNotExists != nil:
+			createIfNotExists++
+		case item.CreateOrReplace != nil:
+			createOrReplace++
+		case item.Delete != nil:
+			deletes++
+		case item.Patch != nil:
+			patches = append(patches, describePatch(item.Patch))
+		}
+	}
+
+	var parts []string
+	addCount := func(n int, singular, plural string) {
+		switch {
+		case n == 1:
+			parts = append(parts, "1 "+singular)
+		case n > 1:
+			parts = append(parts, fmt.Sprintf("%d %s", n, plural))
+		}
+	}
+	addCount(creates, "create", "creates")
+	addCount(createIfNotExists, "createIfNotExists", "createIfNotExists")
+	addCount(createOrReplace, "createOrReplace", "createOrReplace")
+	parts = append(parts, patches...)
+	addCount(deletes, "delete", "deletes")
+	addCount(raw, "raw mutation", "raw mutations")
+
+	if len(parts) == 0 {
+		return "no mutations"
+	}
+	return strings.Join(parts, ", ")
+}
+
+func describePatch(p *api.Patch) string {
+	target := p.ID
+	if target == "" {
+		target = "query " + p.Query
+	}
+
+	var ops []string
+	addPaths := func(op string, paths []string) {
+		sort.Strings(paths)
+		for _, path := range paths {
+			ops = append(ops, op+" "+path)
+		}
+	}
+	addPaths("set", rawMessageKeys(p.Set))
+	addPaths("setIfMissing", rawMessageKeys(p.SetIfMissing))
+	addPaths("unset", append([]string(nil), p.Unset...))
+	addPaths("inc", floatKeys(p.Inc))
+	addPaths("dec", floatKeys(p.Dec))
+	if p.Insert != nil {
+		switch {
+		case p.Insert.Before != "":
+			ops = append(ops, "insert before "+p.Insert.Before)
+		case p.Insert.After != "":
+			ops = append(ops, "insert after "+p.Insert.After)
+		case p.Insert.Replace != "":
+			ops = append(ops, "insert replace "+p.Insert.Replace)
+		}
+	}
+	diffPaths := make([]string, 0, len(p.DiffMatchPatch))
+	for path := range p.DiffMatchPatch {
+		diffPaths = append(diffPaths, path)
+	}
+	addPaths("diffMatchPatch", diffPaths)
+
+	desc := "1 patch on " + target
+	if len(ops) > 0 {
+		desc += " (" + strings.Join(ops, ", ") + ")"
+	}
+	return desc
+}
+
+func rawMessageKeys(m map[string]*json.RawMessage) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+func floatKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -7,9 +7,12 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"net/url"
+	"sort"
 	"testing"
 	"time"
 
+	"github.com/jpillora/backoff"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -69,6 +72,22 @@ func TestMutation_Builder(t *testing.T) {
 				Mutations: []*api.MutationItem{{Delete: &api.Delete{ID: "123"}}},
 			},
 		},
+		{
+			"DeleteByQuery",
+			func(b *sanity.MutationBuilder) {
+				b.DeleteByQuery(`*[_type == $type && count < $max]`,
+					sanity.Param("type", "draft"), sanity.Param("max", 3))
+			},
+			api.MutateRequest{
+				Mutations: []*api.MutationItem{{Delete: &api.Delete{
+					Query: `*[_type == $type && count < $max]`,
+					Params: map[string]*json.RawMessage{
+						"type": mustJSONMsg("draft"),
+						"max":  mustJSONMsg(3),
+					},
+				}}},
+			},
+		},
 		{
 			"empty patch",
 			func(b *sanity.MutationBuilder) {
@@ -118,6 +137,18 @@ func TestMutation_Builder(t *testing.T) {
 				}}},
 			},
 		},
+		{
+			"PatchByQuery",
+			func(b *sanity.MutationBuilder) {
+				b.PatchByQuery("*[_type == 'counter']").Inc("count", 1)
+			},
+			api.MutateRequest{
+				Mutations: []*api.MutationItem{{Patch: &api.Patch{
+					Query: "*[_type == 'counter']",
+					Inc:   map[string]float64{"count": 1},
+				}}},
+			},
+		},
 		{
 			"Patch/Inc",
 			func(b *sanity.MutationBuilder) {
@@ -236,6 +267,60 @@ func TestMutation_Builder(t *testing.T) {
 				}}},
 			},
 		},
+		{
+			"Patch/Append",
+			func(b *sanity.MutationBuilder) {
+				b.Patch("123").Append("array", testDoc, "doink")
+			},
+			api.MutateRequest{
+				Mutations: []*api.MutationItem{{Patch: &api.Patch{
+					ID: "123",
+					Insert: &api.Insert{
+						After: "array[-1]",
+						Items: []*json.RawMessage{
+							mustJSONMsg(testDoc),
+							mustJSONMsg("doink"),
+						},
+					},
+				}}},
+			},
+		},
+		{
+			"Patch/Prepend",
+			func(b *sanity.MutationBuilder) {
+				b.Patch("123").Prepend("array", testDoc, "doink")
+			},
+			api.MutateRequest{
+				Mutations: []*api.MutationItem{{Patch: &api.Patch{
+					ID: "123",
+					Insert: &api.Insert{
+						Before: "array[0]",
+						Items: []*json.RawMessage{
+							mustJSONMsg(testDoc),
+							mustJSONMsg("doink"),
+						},
+					},
+				}}},
+			},
+		},
+		{
+			"Patch/Replace",
+			func(b *sanity.MutationBuilder) {
+				b.Patch("123").Replace("array", testDoc, "doink")
+			},
+			api.MutateRequest{
+				Mutations: []*api.MutationItem{{Patch: &api.Patch{
+					ID: "123",
+					Insert: &api.Insert{
+						Replace: "array[0:]",
+						Items: []*json.RawMessage{
+							mustJSONMsg(testDoc),
+							mustJSONMsg("doink"),
+						},
+					},
+				}}},
+			},
+		},
 	} {
 		t := t
 		t.Run(tc.desc, func(t *testing.T) {
@@ -290,6 +375,16 @@ func TestMutation_Builder_returnIDs(t *testing.T) {
 	})
 }
 
+func TestMutation_Builder_patchTarget(t *testing.T) {
+	withSuite(t, func(s *Suite) {
+		_, err := s.client.Mutate().Patch("").Set("a", 1).End().Do(context.Background())
+		require.Error(t, err)
+
+		_, err = s.client.Mutate().PatchByQuery("").Set("a", 1).End().Do(context.Background())
+		require.Error(t, err)
+	})
+}
+
 func TestMutation_Builder_marshalError(t *testing.T) {
 	withSuite(t, func(s *Suite) {
 		_, err := s.client.Mutate().Create(&testDocumentWithJSONMarshalFailure{}).Do(context.Background())
@@ -318,6 +413,58 @@ func TestMutation_Builder_customJSONMarshaling(t *testing.T) {
 	})
 }
 
+func TestMutation_Builder_raw(t *testing.T) {
+	withSuite(t, func(s *Suite) {
+		raw := `{"patch":{"id":"123","set":{"a":1}}}`
+
+		s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+			b, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+			assert.Equal(t, `{"mutations":[{"delete":{"id":"234"}},`+raw+`]}`, string(b))
+
+			w.WriteHeader(http.StatusOK)
+			_, err = w.Write(mustJSONBytes(&api.MutateResponse{}))
+			assert.NoError(t, err)
+		})
+
+		_, err := s.client.Mutate().Delete("234").Raw(json.RawMessage(raw)).Do(context.Background())
+		require.NoError(t, err)
+	})
+}
+
+func TestMutation_Builder_deleteByQuery(t *testing.T) {
+	withSuite(t, func(s *Suite) {
+		s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+			b, err := ioutil.ReadAll(r.Body)
+			require.NoError(t, err)
+			assert.JSONEq(t, `{"mutations":[
+				{"delete":{"query":"*[_type == \"draft\"]"}},
+				{"delete":{"query":"*[_type == $type]","params":{"type":"post"}}}
+			]}`, string(b))
+
+			w.WriteHeader(http.StatusOK)
+			_, err = w.Write(mustJSONBytes(&api.MutateResponse{}))
+			assert.NoError(t, err)
+		})
+
+		_, err := s.client.Mutate().
+			DeleteByQuery(`*[_type == "draft"]`).
+			DeleteByQuery(`*[_type == $type]`, sanity.Param("type", "post")).
+			Do(context.Background())
+		require.NoError(t, err)
+	})
+
+	t.Run("rejects unmarshalable parameter", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			_, err := s.client.Mutate().
+				DeleteByQuery(`*[_id == $id]`, sanity.Param("id", make(chan int))).
+				Do(context.Background())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), `marshaling parameter "id"`)
+		})
+	})
+}
+
 func TestMutation_Builder_unmarshalResult(t *testing.T) {
 	withSuite(t, func(s *Suite) {
 		s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
@@ -332,6 +479,38 @@ func TestMutation_Builder_unmarshalResult(t *testing.T) {
 	})
 }
 
+func TestMutation_Result_unmarshalDocuments(t *testing.T) {
+	now := time.Date(2020, 1, 2, 23, 01, 44, 0, time.UTC)
+
+	testDoc1 := &testDocument{ID: "1", Type: "doc", CreatedAt: now, UpdatedAt: now, Value: "foo"}
+	testDoc2 := &testDocument{ID: "2", Type: "doc", CreatedAt: now, UpdatedAt: now, Value: "bar"}
+
+	withSuite(t, func(s *Suite) {
+		s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			_, err := w.Write(mustJSONBytes(&api.MutateResponse{
+				Results: []*api.MutateResultItem{
+					{Document: mustJSONMsg(testDoc1)},
+					{},
+					{Document: mustJSONMsg(testDoc2)},
+				},
+			}))
+			assert.NoError(t, err)
+		})
+
+		result, err := s.client.Mutate().
+			CreateOrReplace(testDoc1).
+			Delete("3").
+			CreateOrReplace(testDoc2).
+			Do(context.Background())
+		require.NoError(t, err)
+
+		var docs []testDocument
+		require.NoError(t, result.UnmarshalDocuments(&docs))
+		assert.Equal(t, []testDocument{*testDoc1, {}, *testDoc2}, docs)
+	})
+}
+
 func TestMutation_Builder_transactionID(t *testing.T) {
 	t.Run("can be set", func(t *testing.T) {
 		withSuite(t, func(s *Suite) {
@@ -428,6 +607,77 @@ func TestMutation_Builder_visibilityOption(t *testing.T) {
 	})
 }
 
+func TestMutation_Builder_autoGenerateArrayKeysOption(t *testing.T) {
+	t.Run("can be enabled", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "true", r.URL.Query().Get("autoGenerateArrayKeys"))
+				w.WriteHeader(http.StatusOK)
+				_, err := w.Write(mustJSONBytes(&api.MutateResponse{}))
+				assert.NoError(t, err)
+			})
+
+			_, err := s.client.Mutate().AutoGenerateArrayKeys(true).Do(context.Background())
+			require.NoError(t, err)
+		})
+	})
+
+	t.Run("is not sent by default", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				_, ok := r.URL.Query()["autoGenerateArrayKeys"]
+				assert.False(t, ok)
+				w.WriteHeader(http.StatusOK)
+				_, err := w.Write(mustJSONBytes(&api.MutateResponse{}))
+				assert.NoError(t, err)
+			})
+
+			_, err := s.client.Mutate().Do(context.Background())
+			require.NoError(t, err)
+		})
+	})
+}
+
+func TestMutation_Builder_releaseOptions(t *testing.T) {
+	t.Run("can be set", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "rSummer", r.URL.Query().Get("releaseId"))
+				assert.Equal(t, "true", r.URL.Query().Get("skipCrossDatasetReferenceValidation"))
+				w.WriteHeader(http.StatusOK)
+				_, err := w.Write(mustJSONBytes(&api.MutateResponse{}))
+				assert.NoError(t, err)
+			})
+
+			_, err := s.client.Mutate().
+				ReleaseID("rSummer").
+				SkipCrossDatasetReferenceValidation(true).
+				Do(context.Background())
+			require.NoError(t, err)
+		})
+	})
+
+	t.Run("are not sent by default", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				for _, param := range []string{"releaseId", "skipCrossDatasetReferenceValidation"} {
+					_, ok := r.URL.Query()[param]
+					assert.False(t, ok, param)
+				}
+				w.WriteHeader(http.StatusOK)
+				_, err := w.Write(mustJSONBytes(&api.MutateResponse{}))
+				assert.NoError(t, err)
+			})
+
+			_, err := s.client.Mutate().
+				ReleaseID("").
+				SkipCrossDatasetReferenceValidation(false).
+				Do(context.Background())
+			require.NoError(t, err)
+		})
+	})
+}
+
 func TestMutation_Builder_dryRunOption(t *testing.T) {
 	t.Run("can be set to true", func(t *testing.T) {
 		withSuite(t, func(s *Suite) {
@@ -501,3 +751,665 @@ func TestMutation_Builder_tagOption(t *testing.T) {
 		})
 	})
 }
+
+func TestMutation_retry(t *testing.T) {
+	t.Run("retries with the same transaction ID", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			var transactionIDs []string
+			s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				transactionIDs = append(transactionIDs, r.URL.Query().Get("transactionId"))
+
+				var req api.MutateRequest
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+				assert.Len(t, req.Mutations, 1)
+
+				if len(transactionIDs) <= 2 {
+					w.WriteHeader(http.StatusBadGateway)
+					return
+				}
+
+				w.WriteHeader(http.StatusOK)
+				_, err := w.Write(mustJSONBytes(&api.MutateResponse{}))
+				assert.NoError(t, err)
+			})
+
+			_, err := s.client.Mutate().Delete("123").Do(context.Background())
+			require.NoError(t, err)
+
+			require.Len(t, transactionIDs, 3)
+			assert.NotEmpty(t, transactionIDs[0])
+			assert.Equal(t, transactionIDs[0], transactionIDs[1])
+			assert.Equal(t, transactionIDs[0], transactionIDs[2])
+		},
+			sanity.WithMutationRetry(true),
+			sanity.WithBackoff(backoff.Backoff{Min: time.Millisecond, Max: time.Millisecond}),
+		)
+	})
+
+	t.Run("uses explicit transaction ID", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			var transactionIDs []string
+			s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				transactionIDs = append(transactionIDs, r.URL.Query().Get("transactionId"))
+				if len(transactionIDs) == 1 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+
+				w.WriteHeader(http.StatusOK)
+				_, err := w.Write(mustJSONBytes(&api.MutateResponse{}))
+				assert.NoError(t, err)
+			})
+
+			_, err := s.client.Mutate().TransactionID("x").Do(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, []string{"x", "x"}, transactionIDs)
+		},
+			sanity.WithMutationRetry(true),
+			sanity.WithBackoff(backoff.Backoff{Min: time.Millisecond, Max: time.Millisecond}),
+		)
+	})
+
+	t.Run("not retried by default", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			calls := 0
+			s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				assert.Equal(t, "", r.URL.Query().Get("transactionId"))
+				w.WriteHeader(http.StatusServiceUnavailable)
+			})
+
+			_, err := s.client.Mutate().Do(context.Background())
+			require.Error(t, err)
+			assert.Equal(t, 1, calls)
+		})
+	})
+}
+
+func TestMutation_Builder_invalidateTags(t *testing.T) {
+	for _, tc := range []struct {
+		desc   string
+		dryRun bool
+		expect [][]string
+	}{
+		{"calls hook after mutation", false, [][]string{{"post", "author"}}},
+		{"skips hook on dry run", true, nil},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			var invalidated [][]string
+
+			withSuite(t, func(s *Suite) {
+				s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+					assert.Nil(t, invalidated)
+					w.WriteHeader(http.StatusOK)
+					_, err := w.Write(mustJSONBytes(&api.MutateResponse{}))
+					assert.NoError(t, err)
+				})
+
+				result, err := s.client.Mutate().
+					Delete("123").
+					InvalidateTags("post").
+					InvalidateTags("author").
+					DryRun(tc.dryRun).
+					Do(context.Background())
+				require.NoError(t, err)
+				assert.Equal(t, []string{"post", "author"}, result.InvalidatedTags)
+				assert.Equal(t, tc.expect, invalidated)
+			}, sanity.WithCallbacks(sanity.Callbacks{
+				OnInvalidateTags: func(tags []string) {
+					invalidated = append(invalidated, tags)
+				},
+			}))
+		})
+	}
+
+	t.Run("skips hook on failure", func(t *testing.T) {
+		called := false
+
+		withSuite(t, func(s *Suite) {
+			s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusConflict)
+			})
+
+			_, err := s.client.Mutate().Delete("123").InvalidateTags("post").Do(context.Background())
+			require.Error(t, err)
+			assert.False(t, called)
+		}, sanity.WithCallbacks(sanity.Callbacks{
+			OnInvalidateTags: func(tags []string) { called = true },
+		}))
+	})
+}
+
+func TestMutation_Builder_omitDefaultParams(t *testing.T) {
+	t.Run("omits defaults", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				assert.Empty(t, r.URL.Query())
+				w.WriteHeader(http.StatusOK)
+				_, err := w.Write(mustJSONBytes(&api.MutateResponse{}))
+				assert.NoError(t, err)
+			})
+
+			_, err := s.client.Mutate().OmitDefaultParams(true).ReturnDocuments(false).Do(context.Background())
+			require.NoError(t, err)
+		})
+	})
+
+	t.Run("sends non-defaults", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, url.Values{
+					"returnIds":       {"true"},
+					"returnDocuments": {"true"},
+					"visibility":      {"async"},
+					"dryRun":          {"true"},
+				}, r.URL.Query())
+				w.WriteHeader(http.StatusOK)
+				_, err := w.Write(mustJSONBytes(&api.MutateResponse{}))
+				assert.NoError(t, err)
+			})
+
+			_, err := s.client.Mutate().
+				OmitDefaultParams(true).
+				ReturnIDs(true).
+				Visibility(api.MutationVisibilityAsync).
+				DryRun(true).
+				Do(context.Background())
+			require.NoError(t, err)
+		})
+	})
+
+	t.Run("sends all by default", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, url.Values{
+					"returnIds":       {"false"},
+					"returnDocuments": {"false"},
+					"visibility":      {"sync"},
+					"dryRun":          {"false"},
+				}, r.URL.Query())
+				w.WriteHeader(http.StatusOK)
+				_, err := w.Write(mustJSONBytes(&api.MutateResponse{}))
+				assert.NoError(t, err)
+			})
+
+			_, err := s.client.Mutate().ReturnDocuments(false).Do(context.Background())
+			require.NoError(t, err)
+		})
+	})
+}
+
+func TestMutation_Builder_Describe(t *testing.T) {
+	withSuite(t, func(s *Suite) {
+		assert.Equal(t, "no mutations", s.client.Mutate().Describe())
+
+		mb := s.client.Mutate().
+			Create(map[string]string{"_type": "post"}).
+			Patch("doc1").Set("title", "Hello").Inc("views", 1).End().
+			Create(map[string]string{"_type": "post"}).
+			Delete("doc2").
+			PatchByQuery("*[_type == 'counter']").Unset("b", "a").Append("items", 1).End().
+			Patch("doc3").End().
+			CreateOrReplace(map[string]string{"_id": "doc4"})
+
+		assert.Equal(t,
+			"2 creates, 1 createOrReplace, "+
+				"1 patch on doc1 (set title, inc views), "+
+				"1 patch on query *[_type == 'counter'] (unset a, unset b, insert after items[-1]), "+
+				"1 patch on doc3, "+
+				"1 delete",
+			mb.Describe())
+	})
+}
+
+func TestMutation_Builder_autoKeys(t *testing.T) {
+	type block struct {
+		Key  string `json:"_key,omitempty"`
+		Text string `json:"text"`
+	}
+
+	withSuite(t, func(s *Suite) {
+		s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+			var req struct {
+				Mutations []struct {
+					Patch struct {
+						Insert struct {
+							Items []json.RawMessage `json:"items"`
+						} `json:"insert"`
+					} `json:"patch"`
+				} `json:"mutations"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			require.Len(t, req.Mutations, 2)
+
+			items := req.Mutations[0].Patch.Insert.Items
+			require.Len(t, items, 4)
+
+			var keys []string
+			for _, item := range items[:2] {
+				var m map[string]interface{}
+				require.NoError(t, json.Unmarshal(item, &m))
+				key, ok := m["_key"].(string)
+				require.True(t, ok)
+				assert.Len(t, key, 12)
+				keys = append(keys, key)
+			}
+			assert.NotEqual(t, keys[0], keys[1])
+			assert.Regexp(t, `^\{"_key":"[0-9a-f]{12}","text":"struct"\}$`, string(items[1]))
+
+			assert.JSONEq(t, `{"_key": "existing", "text": "keyed"}`, string(items[2]))
+			assert.Equal(t, `"plain"`, string(items[3]))
+
+			var unkeyed map[string]interface{}
+			require.NoError(t, json.Unmarshal(req.Mutations[1].Patch.Insert.Items[0], &unkeyed))
+			assert.NotContains(t, unkeyed, "_key")
+
+			w.WriteHeader(http.StatusOK)
+			_, err := w.Write(mustJSONBytes(&api.MutateResponse{}))
+			assert.NoError(t, err)
+		})
+
+		mb := s.client.Mutate()
+		mb.Patch("123").WithAutoKeys().Append("body",
+			map[string]interface{}{"text": "map"},
+			block{Text: "struct"},
+			block{Key: "existing", Text: "keyed"},
+			"plain",
+		)
+		mb.Patch("123").Append("body", map[string]interface{}{"text": "map"})
+
+		_, err := mb.Do(context.Background())
+		require.NoError(t, err)
+	})
+}
+
+func TestMutation_Builder_if(t *testing.T) {
+	const cond = `*[_id == $id][0].status == "draft"`
+
+	var perspective string
+	serveCheck := func(s *Suite, ok bool) {
+		s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+			q := r.URL.Query()
+			perspective = q.Get("perspective")
+			assert.Equal(t, `{"ok": (`+cond+`) == true, "revs": *[_id in $__guardIDs]{_id, _rev}}`, q.Get("query"))
+			assert.Equal(t, `"post1"`, q.Get("$id"))
+			assert.Equal(t, `["post1"]`, q.Get("$__guardIDs"))
+
+			_, err := w.Write(mustJSONBytes(&api.QueryResponse{Result: mustJSONMsg(map[string]interface{}{
+				"ok":   ok,
+				"revs": []map[string]string{{"_id": "post1", "_rev": "rev1"}},
+			})}))
+			assert.NoError(t, err)
+		})
+	}
+
+	t.Run("sends guarded mutation when condition holds", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			serveCheck(s, true)
+
+			var received api.MutateRequest
+			s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+				_, err := w.Write(mustJSONBytes(&api.MutateResponse{TransactionID: "tx1"}))
+				assert.NoError(t, err)
+			})
+
+			mb := s.client.Mutate().If(cond, sanity.Param("id", "post1"))
+			mb.Patch("post1").Set("status", "published")
+			mb.Create(map[string]string{"_type": "log"})
+
+			result, err := mb.Do(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, "tx1", result.TransactionID)
+
+			require.Len(t, received.Mutations, 2)
+			assert.Equal(t, "post1", received.Mutations[0].Patch.ID)
+			assert.Equal(t, "rev1", received.Mutations[0].Patch.IfRevisionID)
+			assert.NotNil(t, received.Mutations[1].Create)
+		})
+	})
+
+	t.Run("returns ErrPreconditionFailed when condition does not hold", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			serveCheck(s, false)
+
+			mutated := false
+			s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				mutated = true
+			})
+
+			mb := s.client.Mutate().If(cond, sanity.Param("id", "post1"))
+			mb.Patch("post1").Set("status", "published")
+
+			_, err := mb.Do(context.Background())
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, sanity.ErrPreconditionFailed))
+			assert.False(t, mutated)
+		})
+	})
+	t.Run("checks condition as stored regardless of perspective", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			serveCheck(s, true)
+			s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				_, err := w.Write(mustJSONBytes(&api.MutateResponse{TransactionID: "tx1"}))
+				assert.NoError(t, err)
+			})
+
+			mb := s.client.Mutate().If(cond, sanity.Param("id", "post1"))
+			mb.Patch("post1").Set("status", "published")
+
+			_, err := mb.Do(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, "raw", perspective)
+		}, sanity.WithPerspective("published"))
+	})
+}
+
+func TestMutateResult_WaitVisible(t *testing.T) {
+	serveRevisions := func(s *Suite, polls *int, consistentAfter int) {
+		s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, `["doc1","doc2"]`, sortedIDsParam(t, r.URL.Query().Get("$ids")))
+			*polls++
+
+			docs := []map[string]string{{"_id": "doc1", "_rev": "old"}, {"_id": "doc2", "_rev": "old"}}
+			if *polls >= consistentAfter {
+				docs = []map[string]string{{"_id": "doc1", "_rev": "tx1"}}
+			}
+			_, err := w.Write(mustJSONBytes(&api.QueryResponse{Result: mustJSONMsg(docs)}))
+			assert.NoError(t, err)
+		})
+	}
+
+	result := &sanity.MutateResult{
+		TransactionID: "tx1",
+		Results: []*api.MutateResultItem{
+			{ID: "doc1", Operation: "update"},
+			{ID: "doc2", Operation: "delete"},
+		},
+	}
+
+	t.Run("polls until visible", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			var polls int
+			serveRevisions(s, &polls, 3)
+
+			require.NoError(t, result.WaitVisible(context.Background(), s.client))
+			assert.Equal(t, 3, polls)
+		})
+	})
+
+	t.Run("already visible", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			var polls int
+			serveRevisions(s, &polls, 1)
+
+			require.NoError(t, result.WaitVisible(context.Background(), s.client))
+			assert.Equal(t, 1, polls)
+		})
+	})
+
+	t.Run("bounded by context", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			var polls int
+			serveRevisions(s, &polls, 1000)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 250*time.Millisecond)
+			defer cancel()
+
+			err := result.WaitVisible(ctx, s.client)
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, context.DeadlineExceeded))
+		})
+	})
+
+	t.Run("ignores client perspective", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				// With the published perspective, the draft would never be returned.
+				docs := []map[string]string{}
+				if r.URL.Query().Get("perspective") == "raw" {
+					docs = append(docs, map[string]string{"_id": "drafts.doc1", "_rev": "tx1"})
+				}
+				_, err := w.Write(mustJSONBytes(&api.QueryResponse{Result: mustJSONMsg(docs)}))
+				assert.NoError(t, err)
+			})
+
+			result := &sanity.MutateResult{
+				TransactionID: "tx1",
+				Results:       []*api.MutateResultItem{{ID: "drafts.doc1", Operation: "update"}},
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+			require.NoError(t, result.WaitVisible(ctx, s.client))
+		}, sanity.WithPerspective("published"))
+	})
+
+	t.Run("IDs from returned documents", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, `["doc1"]`, r.URL.Query().Get("$ids"))
+				_, err := w.Write(mustJSONBytes(&api.QueryResponse{
+					Result: mustJSONMsg([]map[string]string{{"_id": "doc1", "_rev": "tx1"}}),
+				}))
+				assert.NoError(t, err)
+			})
+
+			result := &sanity.MutateResult{
+				TransactionID: "tx1",
+				Results:       []*api.MutateResultItem{{Document: mustJSONMsg(map[string]string{"_id": "doc1"})}},
+			}
+			require.NoError(t, result.WaitVisible(context.Background(), s.client))
+		})
+	})
+
+	t.Run("requires document IDs", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			result := &sanity.MutateResult{TransactionID: "tx1", Results: []*api.MutateResultItem{{}}}
+			err := result.WaitVisible(context.Background(), s.client)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "no document IDs")
+		})
+	})
+}
+
+// sortedIDsParam returns the JSON array of IDs in a query parameter, sorted.
+func sortedIDsParam(t *testing.T, param string) string {
+	var ids []string
+	require.NoError(t, json.Unmarshal([]byte(param), &ids))
+	sort.Strings(ids)
+	return string(mustJSONBytes(ids))
+}
+
+func TestMutation_Builder_Reset(t *testing.T) {
+	withSuite(t, func(s *Suite) {
+		var bodies []string
+		s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "async", r.URL.Query().Get("visibility"))
+			b, err := ioutil.ReadAll(r.Body)
+			assert.NoError(t, err)
+			bodies = append(bodies, string(b))
+
+			_, err = w.Write(mustJSONBytes(&api.MutateResponse{}))
+			assert.NoError(t, err)
+		})
+
+		mb := s.client.Mutate().Visibility(api.MutationVisibilityAsync)
+		assert.Equal(t, 0, mb.Len())
+
+		for _, id := range []string{"a", "b"} {
+			mb.Delete(id).Patch(id + "-ref").Unset("ref")
+			assert.Equal(t, 2, mb.Len())
+
+			_, err := mb.Do(context.Background())
+			require.NoError(t, err)
+
+			assert.Equal(t, 0, mb.Reset().Len())
+		}
+
+		assert.Equal(t, []string{
+			`{"mutations":[{"delete":{"id":"a"}},{"patch":{"id":"a-ref","unset":["ref"]}}]}`,
+			`{"mutations":[{"delete":{"id":"b"}},{"patch":{"id":"b-ref","unset":["ref"]}}]}`,
+		}, bodies)
+	})
+
+	t.Run("clears error", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				b, err := ioutil.ReadAll(r.Body)
+				assert.NoError(t, err)
+				assert.Equal(t, `{"mutations":[{"delete":{"id":"a"}}]}`, string(b))
+
+				_, err = w.Write(mustJSONBytes(&api.MutateResponse{}))
+				assert.NoError(t, err)
+			})
+
+			mb := s.client.Mutate().Create(&testDocumentWithJSONMarshalFailure{})
+			_, err := mb.Do(context.Background())
+			require.Error(t, err)
+
+			_, err = mb.Reset().Delete("a").Do(context.Background())
+			require.NoError(t, err)
+		})
+	})
+}
+
+func TestMutation_Builder_idGenerator(t *testing.T) {
+	t.Run("injects IDs only when absent", func(t *testing.T) {
+		var n int
+		gen := func() string {
+			n++
+			return fmt.Sprintf("gen%d", n)
+		}
+
+		withSuite(t, func(s *Suite) {
+			s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				b, err := ioutil.ReadAll(r.Body)
+				assert.NoError(t, err)
+				assert.Equal(t, `{"mutations":[`+
+					`{"create":{"_id":"gen1","_type":"post"}},`+
+					`{"create":{"_id":"explicit","_type":"post"}},`+
+					`{"createOrReplace":{"_id":"gen2","_type":"post","value":"x"}},`+
+					`{"createOrReplace":{"_id":"gen3","_createdAt":"0001-01-01T00:00:00Z","_type":"doc","_updatedAt":"0001-01-01T00:00:00Z","value":""}},`+
+					`{"createIfNotExists":{"_type":"post"}}]}`, string(b))
+
+				_, err = w.Write(mustJSONBytes(&api.MutateResponse{}))
+				assert.NoError(t, err)
+			})
+
+			_, err := s.client.Mutate().
+				Create(map[string]interface{}{"_type": "post"}).
+				Create(map[string]interface{}{"_id": "explicit", "_type": "post"}).
+				CreateOrReplace(json.RawMessage(`{"_type":"post","value":"x"}`)).
+				CreateOrReplace(&testDocument{Type: "doc"}).
+				CreateIfNotExists(map[string]interface{}{"_type": "post"}).
+				Do(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, 3, n)
+		}, sanity.WithIDGenerator(gen))
+	})
+
+	t.Run("not used by default", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				b, err := ioutil.ReadAll(r.Body)
+				assert.NoError(t, err)
+				assert.Equal(t, `{"mutations":[{"create":{"_type":"post"}}]}`, string(b))
+
+				_, err = w.Write(mustJSONBytes(&api.MutateResponse{}))
+				assert.NoError(t, err)
+			})
+
+			_, err := s.client.Mutate().Create(map[string]interface{}{"_type": "post"}).Do(context.Background())
+			require.NoError(t, err)
+		})
+	})
+
+	t.Run("empty generated ID", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			_, err := s.client.Mutate().Create(map[string]interface{}{"_type": "post"}).Do(context.Background())
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "empty ID")
+		}, sanity.WithIDGenerator(func() string { return "" }))
+	})
+}
+
+func TestMutation_Builder_DoWithResponse(t *testing.T) {
+	withSuite(t, func(s *Suite) {
+		s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-RateLimit-Remaining-Second", "7")
+			_, err := w.Write(mustJSONBytes(&api.MutateResponse{TransactionID: "tx1"}))
+			assert.NoError(t, err)
+		})
+
+		result, resp, err := s.client.Mutate().Delete("a").DoWithResponse(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, "tx1", result.TransactionID)
+		assert.Equal(t, "7", resp.Header.Get("X-RateLimit-Remaining-Second"))
+
+		var body api.MutateResponse
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, "tx1", body.TransactionID)
+	})
+}
+
+func TestMutation_Builder_targetDrafts(t *testing.T) {
+	withSuite(t, func(s *Suite) {
+		s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+			var req struct {
+				Mutations []json.RawMessage `json:"mutations"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			require.Len(t, req.Mutations, 9)
+
+			got := make([]string, len(req.Mutations))
+			for i, m := range req.Mutations {
+				got[i] = string(m)
+			}
+			assert.Equal(t, []string{
+				`{"create":{"_type":"post","_id":"drafts.a","title":"A"}}`,
+				`{"createIfNotExists":{"_id":"drafts.b"}}`,
+				`{"createOrReplace":{"_id":"drafts.c"}}`,
+				`{"delete":{"id":"drafts.d"}}`,
+				`{"patch":{"id":"drafts.e","set":{"x":1}}}`,
+				`{"patch":{"id":"drafts.f","unset":["x"]}}`,
+				`{"patch":{"query":"*[_type == 'post']","unset":["x"]}}`,
+				`{"delete":{"query":"*[_type == 'old']"}}`,
+			}, got[:8])
+			assert.Regexp(t, `^\{"create":\{"_id":"drafts\.[0-9a-f]{32}","_type":"post"\}\}$`, got[8])
+
+			_, err := w.Write(mustJSONBytes(&api.MutateResponse{}))
+			assert.NoError(t, err)
+		})
+
+		mb := s.client.Mutate().TargetDrafts(true).
+			Create(json.RawMessage(`{"_type": "post", "_id": "a", "title": "A"}`)).
+			CreateIfNotExists(map[string]string{"_id": "b"}).
+			CreateOrReplace(map[string]string{"_id": "drafts.c"}).
+			Delete("d")
+		mb.Patch("e").Set("x", 1)
+		mb.Patch("drafts.f").Unset("x")
+		mb.PatchByQuery("*[_type == 'post']").Unset("x")
+		mb.DeleteByQuery("*[_type == 'old']").
+			Create(map[string]string{"_type": "post"})
+
+		_, err := mb.Do(context.Background())
+		require.NoError(t, err)
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				b, err := ioutil.ReadAll(r.Body)
+				assert.NoError(t, err)
+				assert.Equal(t, `{"mutations":[{"delete":{"id":"d"}}]}`, string(b))
+
+				_, err = w.Write(mustJSONBytes(&api.MutateResponse{}))
+				assert.NoError(t, err)
+			})
+
+			_, err := s.client.Mutate().Delete("d").Do(context.Background())
+			require.NoError(t, err)
+		})
+	})
+}
@@ -0,0 +1,117 @@
+package sanity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	visiblePollMin = 100 * time.Millisecond
+	visiblePollMax = 2 * time.Second
+)
+
+// WaitVisible waits until the documents affected by the mutation are visible to queries,
+// which with the async and deferred visibilities happens some time after Do returns. The
+// client must be configured for the dataset the mutation was applied to. It returns the
+// context's error if it is done first, so the wait should be bounded with a deadline.
+//
+// The API offers no way to be notified, so the documents are polled with a query to the
+// API host until each of them has the transaction ID as its revision, or is gone if it was
+// deleted. The first poll is immediate, and the interval then doubles from 100ms up to 2s;
+// every poll is an API request that counts towards the project's quota. If another
+// transaction changes a document before it is seen, its revision never matches, and
+// WaitVisible waits until the context is done.
+//
+// The documents are taken from the results, so the mutation must have been performed with
+// ReturnIDs or ReturnDocuments, and not as a dry run.
+func (r *MutateResult) WaitVisible(ctx context.Context, c *Client) error {
+	if r.TransactionID == "" {
+		return errors.New("waiting for visibility: result has no transaction ID")
+	}
+
+	deleted, err := r.affectedDocuments()
+	if err != nil {
+		return fmt.Errorf("waiting for visibility: %w", err)
+	}
+	ids := make([]string, 0, len(deleted))
+	for id := range deleted {
+		ids = append(ids, id)
+	}
+
+	qb := c.storedQuery(`*[_id in $ids]{_id, _rev}`).Param("ids", ids)
+
+	for delay := visiblePollMin; ; delay = min(2*delay, visiblePollMax) {
+		visible, err := r.visible(ctx, qb, deleted)
+		if err != nil {
+			return fmt.Errorf("waiting for visibility: %w", err)
+		}
+		if visible {
+			return nil
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// affectedDocuments returns the IDs of the documents affected by the mutation, mapped to
+// whether the document was deleted.
+func (r *MutateResult) affectedDocuments() (map[string]bool, error) {
+	deleted := make(map[string]bool, len(r.Results))
+	for _, item := range r.Results {
+		if item == nil {
+			continue
+		}
+
+		id := item.ID
+		if id == "" && item.Document != nil {
+			var doc struct {
+				ID string `json:"_id"`
+			}
+			if err := json.Unmarshal(*item.Document, &doc); err != nil {
+				return nil, fmt.Errorf("decoding document: %w", err)
+			}
+			id = doc.ID
+		}
+		if id != "" {
+			deleted[id] = item.Operation == "delete"
+		}
+	}
+
+	if len(deleted) == 0 {
+		return nil, errors.New("result has no document IDs; perform the mutation with ReturnIDs or ReturnDocuments")
+	}
+	return deleted, nil
+}
+
+// visible reports whether every affected document has been seen in its state after the
+// transaction.
+func (r *MutateResult) visible(ctx context.Context, qb *QueryBuilder, deleted map[string]bool) (bool, error) {
+	result, err := qb.Do(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	var docs []struct {
+		ID  string `json:"_id"`
+		Rev string `json:"_rev"`
+	}
+	if err := result.Unmarshal(&docs); err != nil {
+		return false, fmt.Errorf("decoding revisions: %w", err)
+	}
+
+	revs := make(map[string]string, len(docs))
+	for _, doc := range docs {
+		revs[doc.ID] = doc.Rev
+	}
+	for id, isDeleted := range deleted {
+		rev, ok := revs[id]
+		if isDeleted && ok || !isDeleted && rev != r.TransactionID {
+			return false, nil
+		}
+	}
+	return true, nil
+}
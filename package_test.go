@@ -20,7 +20,7 @@ type Suite struct {
 	client *sanity.Client
 }
 
-func withSuite(t *testing.T, f func(*Suite), opts ...sanity.Option) {
+func withSuite(t testing.TB, f func(*Suite), opts ...sanity.Option) {
 	t.Helper()
 
 	mux := chi.NewRouter()
@@ -0,0 +1,90 @@
+package sanity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Paginate returns an iterator that fetches the results of the query in pages of pageSize
+// documents, by slicing the query as (query)[start...end]. The query must return an array
+// in a stable order, such as by ending with | order(_id), or pages may overlap or skip
+// documents. Every page is requested with the tag and parameters of the query builder.
+func (qb *QueryBuilder) Paginate(pageSize int) *PageIterator {
+	it := &PageIterator{qb: qb, pageSize: pageSize, limit: -1}
+	if pageSize <= 0 {
+		it.err = errors.New("page size must be positive")
+	}
+	return it
+}
+
+// Paginate returns an iterator over the results of the query in pages of pageSize documents.
+// It is a shorthand for Query(query) with the parameters, followed by QueryBuilder.Paginate.
+func (c *Client) Paginate(query string, pageSize int, params ...QueryParam) *PageIterator {
+	return c.Query(query).withParams(params).Paginate(pageSize)
+}
+
+// PageIterator iterates over the pages of a query. It is not safe for concurrent use.
+type PageIterator struct {
+	qb       *QueryBuilder
+	pageSize int
+	limit    int
+	offset   int
+	done     bool
+	err      error
+}
+
+// Limit caps the total number of documents returned across all pages. Iteration stops once
+// maxTotal documents have been returned, even if that is in the middle of a page.
+func (it *PageIterator) Limit(maxTotal int) *PageIterator {
+	if maxTotal < 0 {
+		it.err = errors.New("limit cannot be negative")
+	}
+	it.limit = maxTotal
+	return it
+}
+
+// Next fetches the next page and returns its documents. When there are no more pages,
+// io.EOF is returned. On API failure, this will return an error of type *RequestError.
+func (it *PageIterator) Next(ctx context.Context) ([]json.RawMessage, error) {
+	if it.err != nil {
+		return nil, fmt.Errorf("paginate: %w", it.err)
+	}
+	if it.done {
+		return nil, io.EOF
+	}
+
+	end := it.offset + it.pageSize
+	if it.limit >= 0 && end > it.limit {
+		end = it.limit
+	}
+	if end <= it.offset {
+		it.done = true
+		return nil, io.EOF
+	}
+
+	page := *it.qb
+	page.query = fmt.Sprintf("(%s)[%d...%d]", it.qb.composedQuery(), it.offset, end)
+	page.fragments = nil
+
+	result, err := page.Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var docs []json.RawMessage
+	if err := result.Unmarshal(&docs); err != nil {
+		return nil, fmt.Errorf("paginate: decoding page: %w", err)
+	}
+
+	if len(docs) < end-it.offset {
+		it.done = true
+	}
+	it.offset += len(docs)
+	if len(docs) == 0 {
+		return nil, io.EOF
+	}
+	return docs, nil
+}
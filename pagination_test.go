@@ -0,0 +1,206 @@
+package sanity_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sanity "github.com/sanity-io/client-go"
+	"github.com/sanity-io/client-go/api"
+)
+
+// servePages serves a query over total documents, honoring the slice appended by Paginate.
+func servePages(t *testing.T, s *Suite, total int) *[]string {
+	var queries []string
+	slice := regexp.MustCompile(`^\(\*\[_type == "post"\] \| order\(_id\)\)\[(\d+)\.\.\.(\d+)\]$`)
+
+	s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query().Get("query")
+		queries = append(queries, query)
+
+		m := slice.FindStringSubmatch(query)
+		require.NotNil(t, m, query)
+		start, _ := strconv.Atoi(m[1])
+		end, _ := strconv.Atoi(m[2])
+
+		docs := []map[string]string{}
+		for i := start; i < end && i < total; i++ {
+			docs = append(docs, map[string]string{"_id": fmt.Sprintf("doc%d", i)})
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_, err := w.Write(mustJSONBytes(&api.QueryResponse{Result: mustJSONMsg(docs)}))
+		assert.NoError(t, err)
+	})
+	return &queries
+}
+
+func collectPages(t *testing.T, next func(context.Context) ([]json.RawMessage, error)) ([]string, int) {
+	var ids []string
+	pages := 0
+	for {
+		docs, err := next(context.Background())
+		if err == io.EOF {
+			return ids, pages
+		}
+		require.NoError(t, err)
+
+		pages++
+		for _, raw := range docs {
+			var doc struct {
+				ID string `json:"_id"`
+			}
+			require.NoError(t, json.Unmarshal(raw, &doc))
+			ids = append(ids, doc.ID)
+		}
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	query := `*[_type == "post"] | order(_id)`
+
+	t.Run("fetches all pages", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			queries := servePages(t, s, 7)
+
+			ids, pages := collectPages(t, s.client.Query(query).Paginate(3).Next)
+			assert.Len(t, ids, 7)
+			assert.Equal(t, "doc6", ids[6])
+			assert.Equal(t, 3, pages)
+			assert.Len(t, *queries, 3)
+		})
+	})
+
+	t.Run("stops at limit mid-page", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			queries := servePages(t, s, 100)
+
+			ids, pages := collectPages(t, s.client.Query(query).Paginate(3).Limit(7).Next)
+			assert.Equal(t, []string{"doc0", "doc1", "doc2", "doc3", "doc4", "doc5", "doc6"}, ids)
+			assert.Equal(t, 3, pages)
+			assert.Equal(t, []string{
+				`(*[_type == "post"] | order(_id))[0...3]`,
+				`(*[_type == "post"] | order(_id))[3...6]`,
+				`(*[_type == "post"] | order(_id))[6...7]`,
+			}, *queries)
+		})
+	})
+
+	t.Run("stops when results run out before limit", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			servePages(t, s, 4)
+
+			ids, _ := collectPages(t, s.client.Query(query).Paginate(3).Limit(10).Next)
+			assert.Len(t, ids, 4)
+		})
+	})
+
+	t.Run("zero limit fetches nothing", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			queries := servePages(t, s, 4)
+
+			ids, _ := collectPages(t, s.client.Query(query).Paginate(3).Limit(0).Next)
+			assert.Empty(t, ids)
+			assert.Empty(t, *queries)
+		})
+	})
+
+	t.Run("rejects invalid page size", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			_, err := s.client.Query(query).Paginate(0).Next(context.Background())
+			require.Error(t, err)
+		})
+	})
+}
+
+func TestClient_Paginate(t *testing.T) {
+	query := `*[_type == "post"] | order(_id)`
+
+	t.Run("fetches pages up to the final partial page", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			queries := servePages(t, s, 8)
+
+			it := s.client.Paginate(query, 3)
+			for _, want := range [][]string{{"doc0", "doc1", "doc2"}, {"doc3", "doc4", "doc5"}, {"doc6", "doc7"}} {
+				docs, err := it.Next(context.Background())
+				require.NoError(t, err)
+
+				ids := make([]string, len(docs))
+				for i, raw := range docs {
+					var doc struct {
+						ID string `json:"_id"`
+					}
+					require.NoError(t, json.Unmarshal(raw, &doc))
+					ids[i] = doc.ID
+				}
+				assert.Equal(t, want, ids)
+			}
+
+			_, err := it.Next(context.Background())
+			assert.Equal(t, io.EOF, err)
+			assert.Equal(t, []string{
+				"(" + query + ")[0...3]",
+				"(" + query + ")[3...6]",
+				"(" + query + ")[6...9]",
+			}, *queries)
+		})
+	})
+
+	t.Run("sends parameters", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, `"post"`, r.URL.Query().Get("$type"))
+				_, err := w.Write(mustJSONBytes(&api.QueryResponse{Result: mustJSONMsg([]string{})}))
+				assert.NoError(t, err)
+			})
+
+			_, err := s.client.Paginate(`*[_type == $type]`, 10, sanity.Param("type", "post")).Next(context.Background())
+			assert.Equal(t, io.EOF, err)
+		})
+	})
+
+	t.Run("tags every page", func(t *testing.T) {
+		for _, tc := range []struct {
+			desc string
+			ctx  context.Context
+			tag  string
+			want string
+		}{
+			{desc: "builder tag", ctx: context.Background(), tag: "pages", want: "pages"},
+			{desc: "context tag", ctx: sanity.ContextWithTag(context.Background(), "ctx"), want: "ctx"},
+			{desc: "builder tag over context tag", ctx: sanity.ContextWithTag(context.Background(), "ctx"), tag: "pages", want: "pages"},
+		} {
+			tc := tc
+			t.Run(tc.desc, func(t *testing.T) {
+				withSuite(t, func(s *Suite) {
+					var tags []string
+					s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+						tags = append(tags, r.URL.Query().Get("tag"))
+						docs := []string{"a", "b"}
+						if len(tags) > 1 {
+							docs = docs[:1]
+						}
+						_, err := w.Write(mustJSONBytes(&api.QueryResponse{Result: mustJSONMsg(docs)}))
+						assert.NoError(t, err)
+					})
+
+					it := s.client.Query(query).Tag(tc.tag).Paginate(2)
+					_, err := it.Next(tc.ctx)
+					require.NoError(t, err)
+					_, err = it.Next(tc.ctx)
+					require.NoError(t, err)
+
+					assert.Equal(t, []string{tc.want, tc.want}, tags)
+				}, sanity.WithTag("default"))
+			})
+		}
+	})
+}
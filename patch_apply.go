@@ -0,0 +1,506 @@
+package sanity
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sanity-io/client-go/api"
+)
+
+// ApplyMutations applies mutations, such as those received in a listener mutation event, to
+// a locally held copy of a document and returns the updated document. This makes it possible
+// to keep a cached document up to date without querying it again. Mutations that target other
+// documents are ignored. If the document is deleted, the result is nil.
+//
+// Only the subset of patch operations that the client can model is supported: set,
+// setIfMissing, unset, inc, dec and insert, applied in that order within a patch, as the API
+// does. Paths may
+// use attribute access (a.b or a["b"]), array indexes (a[0], a[-1]) and key selectors
+// (a[_key=="x"]). Patches selecting documents by query, diffMatchPatch operations and other
+// path expressions result in an error.
+func ApplyMutations(doc json.RawMessage, mutations ...*api.MutationItem) (json.RawMessage, error) {
+	var state interface{}
+	if len(bytes.TrimSpace(doc)) > 0 {
+		dec := json.NewDecoder(bytes.NewReader(doc))
+		dec.UseNumber()
+		if err := dec.Decode(&state); err != nil {
+			return nil, fmt.Errorf("decoding document: %w", err)
+		}
+	}
+
+	for i, m := range mutations {
+		var err error
+		if state, err = applyMutation(state, m); err != nil {
+			return nil, fmt.Errorf("mutation %d: %w", i, err)
+		}
+	}
+
+	if state == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(state)
+	if err != nil {
+		return nil, fmt.Errorf("encoding document: %w", err)
+	}
+	return b, nil
+}
+
+func applyMutation(state interface{}, m *api.MutationItem) (interface{}, error) {
+	id := documentID(state)
+
+	switch {
+	case m.Raw != nil:
+		return nil, errors.New("raw mutations are not supported")
+	case m.Create != nil, m.CreateIfNotExists != nil, m.CreateOrReplace != nil:
+		var raw *json.RawMessage
+		switch {
+		case m.Create != nil:
+			raw = m.Create
+		case m.CreateIfNotExists != nil:
+			raw = m.CreateIfNotExists
+		default:
+			raw = m.CreateOrReplace
+		}
+
+		var doc interface{}
+		dec := json.NewDecoder(bytes.NewReader(*raw))
+		dec.UseNumber()
+		if err := dec.Decode(&doc); err != nil {
+			return nil, fmt.Errorf("decoding created document: %w", err)
+		}
+		if id != "" && documentID(doc) != id {
+			return state, nil
+		}
+		if m.CreateOrReplace == nil && state != nil {
+			return state, nil
+		}
+		return doc, nil
+	case m.Delete != nil:
+		if m.Delete.ID == "" {
+			return nil, errors.New("deleting by query is not supported")
+		}
+		if m.Delete.ID == id {
+			return nil, nil
+		}
+		return state, nil
+	case m.Patch != nil:
+		if m.Patch.ID == "" {
+			return nil, errors.New("patching by query is not supported")
+		}
+		if m.Patch.ID != id {
+			return state, nil
+		}
+		return applyPatch(state, m.Patch)
+	default:
+		return state, nil
+	}
+}
+
+func applyPatch(state interface{}, p *api.Patch) (interface{}, error) {
+	if len(p.DiffMatchPatch) > 0 {
+		return nil, errors.New("diffMatchPatch is not supported")
+	}
+
+	for _, path := range sortedKeys(p.Set) {
+		val, err := decodeValue(p.Set[path])
+		if err != nil {
+			return nil, err
+		}
+		if state, err = setPath(state, path, val, false); err != nil {
+			return nil, fmt.Errorf("set %q: %w", path, err)
+		}
+	}
+
+	for _, path := range sortedKeys(p.SetIfMissing) {
+		val, err := decodeValue(p.SetIfMissing[path])
+		if err != nil {
+			return nil, err
+		}
+		if state, err = setPath(state, path, val, true); err != nil {
+			return nil, fmt.Errorf("setIfMissing %q: %w", path, err)
+		}
+	}
+
+	for _, path := range p.Unset {
+		var err error
+		if state, err = unsetPath(state, path); err != nil {
+			return nil, fmt.Errorf("unset %q: %w", path, err)
+		}
+	}
+
+	for _, op := range []struct {
+		name  string
+		vals  map[string]float64
+		delta float64
+	}{{"inc", p.Inc, 1}, {"dec", p.Dec, -1}} {
+		paths := make([]string, 0, len(op.vals))
+		for path := range op.vals {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+
+		for _, path := range paths {
+			var err error
+			if state, err = incPath(state, path, op.delta*op.vals[path]); err != nil {
+				return nil, fmt.Errorf("%s %q: %w", op.name, path, err)
+			}
+		}
+	}
+
+	if p.Insert != nil {
+		var err error
+		if state, err = insertAt(state, p.Insert); err != nil {
+			return nil, fmt.Errorf("insert: %w", err)
+		}
+	}
+
+	return state, nil
+}
+
+func documentID(doc interface{}) string {
+	if m, ok := doc.(map[string]interface{}); ok {
+		if id, ok := m["_id"].(string); ok {
+			return id
+		}
+	}
+	return ""
+}
+
+func decodeValue(raw *json.RawMessage) (interface{}, error) {
+	if raw == nil {
+		return nil, nil
+	}
+
+	var val interface{}
+	dec := json.NewDecoder(bytes.NewReader(*raw))
+	dec.UseNumber()
+	if err := dec.Decode(&val); err != nil {
+		return nil, fmt.Errorf("decoding value: %w", err)
+	}
+	return val, nil
+}
+
+func sortedKeys(m map[string]*json.RawMessage) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// pathSegment is a single step of a patch path: an attribute, an array index or a key
+// selector.
+type pathSegment struct {
+	attr  string
+	index *int
+	key   *string
+}
+
+func parsePath(path string) ([]pathSegment, error) {
+	var segs []pathSegment
+	rest := strings.TrimSpace(path)
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			if len(segs) == 0 {
+				return nil, fmt.Errorf("invalid path %q", path)
+			}
+			rest = rest[1:]
+		case '[':
+			end := closingBracket(rest)
+			if end < 0 {
+				return nil, fmt.Errorf("invalid path %q", path)
+			}
+			seg, err := parseSelector(strings.TrimSpace(rest[1:end]))
+			if err != nil {
+				return nil, fmt.Errorf("invalid path %q: %w", path, err)
+			}
+			segs = append(segs, seg)
+			rest = rest[end+1:]
+		default:
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			name := rest[:end]
+			if !regExpIdentifier.MatchString(name) {
+				return nil, fmt.Errorf("invalid path %q", path)
+			}
+			segs = append(segs, pathSegment{attr: name})
+			rest = rest[end:]
+		}
+	}
+	if len(segs) == 0 {
+		return nil, fmt.Errorf("invalid path %q", path)
+	}
+	return segs, nil
+}
+
+func closingBracket(s string) int {
+	var quote byte
+	for i := 1; i < len(s); i++ {
+		switch {
+		case quote != 0 && s[i] == '\\':
+			i++
+		case quote != 0 && s[i] == quote:
+			quote = 0
+		case quote == 0 && (s[i] == '"' || s[i] == '\''):
+			quote = s[i]
+		case quote == 0 && s[i] == ']':
+			return i
+		}
+	}
+	return -1
+}
+
+func parseSelector(sel string) (pathSegment, error) {
+	if n, err := strconv.Atoi(sel); err == nil {
+		return pathSegment{index: &n}, nil
+	}
+
+	if s, ok := unquote(sel); ok {
+		return pathSegment{attr: s}, nil
+	}
+
+	if parts := strings.SplitN(sel, "==", 2); len(parts) == 2 && strings.TrimSpace(parts[0]) == "_key" {
+		if key, ok := unquote(strings.TrimSpace(parts[1])); ok {
+			return pathSegment{key: &key}, nil
+		}
+	}
+
+	return pathSegment{}, fmt.Errorf("unsupported selector [%s]", sel)
+}
+
+func unquote(s string) (string, bool) {
+	if len(s) < 2 {
+		return "", false
+	}
+	if s[0] == '\'' && s[len(s)-1] == '\'' {
+		s = `"` + strings.ReplaceAll(s[1:len(s)-1], `"`, `\"`) + `"`
+	}
+	if s[0] != '"' {
+		return "", false
+	}
+	u, err := strconv.Unquote(s)
+	return u, err == nil
+}
+
+// resolveIndex returns the position in arr selected by seg, or -1 if there is none.
+func resolveIndex(arr []interface{}, seg pathSegment) int {
+	if seg.index != nil {
+		i := *seg.index
+		if i < 0 {
+			i += len(arr)
+		}
+		if i < 0 || i >= len(arr) {
+			return -1
+		}
+		return i
+	}
+	for i, elem := range arr {
+		if m, ok := elem.(map[string]interface{}); ok && m["_key"] == *seg.key {
+			return i
+		}
+	}
+	return -1
+}
+
+// updatePath calls fn with the value at the path (or nil if missing) and stores the value
+// it returns. If fn returns remove, the value is removed instead. Missing objects along the
+// path are created when create is set; otherwise, the update is skipped.
+func updatePath(
+	node interface{}, segs []pathSegment, create bool,
+	fn func(val interface{}, exists bool) (result interface{}, remove bool, err error),
+) (interface{}, error) {
+	seg := segs[0]
+
+	if seg.index == nil && seg.key == nil {
+		obj, ok := node.(map[string]interface{})
+		if !ok {
+			if node != nil || !create {
+				return node, nil
+			}
+			obj = map[string]interface{}{}
+		}
+
+		child, exists := obj[seg.attr]
+		if len(segs) > 1 {
+			if !exists && !create {
+				return node, nil
+			}
+			updated, err := updatePath(child, segs[1:], create, fn)
+			if err != nil {
+				return nil, err
+			}
+			obj[seg.attr] = updated
+			return obj, nil
+		}
+
+		result, remove, err := fn(child, exists)
+		if err != nil {
+			return nil, err
+		}
+		if remove {
+			delete(obj, seg.attr)
+		} else {
+			obj[seg.attr] = result
+		}
+		return obj, nil
+	}
+
+	arr, ok := node.([]interface{})
+	if !ok {
+		return node, nil
+	}
+	i := resolveIndex(arr, seg)
+	if i < 0 {
+		return node, nil
+	}
+
+	if len(segs) > 1 {
+		updated, err := updatePath(arr[i], segs[1:], create, fn)
+		if err != nil {
+			return nil, err
+		}
+		arr[i] = updated
+		return arr, nil
+	}
+
+	result, remove, err := fn(arr[i], true)
+	if err != nil {
+		return nil, err
+	}
+	if remove {
+		return append(arr[:i:i], arr[i+1:]...), nil
+	}
+	arr[i] = result
+	return arr, nil
+}
+
+func setPath(state interface{}, path string, val interface{}, ifMissing bool) (interface{}, error) {
+	segs, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return updatePath(state, segs, true, func(cur interface{}, exists bool) (interface{}, bool, error) {
+		if ifMissing && exists {
+			return cur, false, nil
+		}
+		return val, false, nil
+	})
+}
+
+func unsetPath(state interface{}, path string) (interface{}, error) {
+	segs, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return updatePath(state, segs, false, func(cur interface{}, exists bool) (interface{}, bool, error) {
+		return nil, true, nil
+	})
+}
+
+func incPath(state interface{}, path string, delta float64) (interface{}, error) {
+	segs, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	return updatePath(state, segs, false, func(cur interface{}, exists bool) (interface{}, bool, error) {
+		if !exists {
+			return nil, true, nil
+		}
+		n, ok := cur.(json.Number)
+		if !ok {
+			return cur, false, nil
+		}
+		f, err := n.Float64()
+		if err != nil {
+			return nil, false, fmt.Errorf("parsing number %s: %w", n, err)
+		}
+		return json.Number(strconv.FormatFloat(f+delta, 'f', -1, 64)), false, nil
+	})
+}
+
+func insertAt(state interface{}, ins *api.Insert) (interface{}, error) {
+	var path string
+	var offset int
+	replace := false
+	switch {
+	case ins.Before != "":
+		path = ins.Before
+	case ins.After != "":
+		path, offset = ins.After, 1
+	case ins.Replace != "":
+		path, replace = ins.Replace, true
+	default:
+		return nil, errors.New("no position given")
+	}
+
+	segs, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+	last := segs[len(segs)-1]
+	if last.index == nil && last.key == nil {
+		return nil, fmt.Errorf("path %q does not select an array element", path)
+	}
+
+	items := make([]interface{}, len(ins.Items))
+	for i, raw := range ins.Items {
+		if items[i], err = decodeValue(raw); err != nil {
+			return nil, err
+		}
+	}
+
+	splice := func(arr []interface{}) []interface{} {
+		var pos int
+		switch {
+		case last.index != nil && *last.index < 0:
+			pos = len(arr) + *last.index
+			if pos < 0 {
+				pos = 0
+			}
+		case last.index != nil:
+			pos = *last.index
+			if pos > len(arr) {
+				pos = len(arr)
+			}
+		default:
+			if pos = resolveIndex(arr, last); pos < 0 {
+				return arr
+			}
+		}
+
+		end := pos
+		if replace {
+			if pos < len(arr) {
+				end = pos + 1
+			}
+		} else if pos < len(arr) {
+			pos += offset
+			end = pos
+		}
+
+		result := make([]interface{}, 0, len(arr)+len(items))
+		result = append(result, arr[:pos]...)
+		result = append(result, items...)
+		return append(result, arr[end:]...)
+	}
+
+	if len(segs) == 1 {
+		return nil, fmt.Errorf("path %q does not select an array element", path)
+	}
+	return updatePath(state, segs[:len(segs)-1], false, func(cur interface{}, exists bool) (interface{}, bool, error) {
+		arr, ok := cur.([]interface{})
+		if !ok {
+			return cur, false, nil
+		}
+		return splice(arr), false, nil
+	})
+}
@@ -0,0 +1,199 @@
+package sanity_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sanity "github.com/sanity-io/client-go"
+	"github.com/sanity-io/client-go/api"
+)
+
+func TestApplyMutations(t *testing.T) {
+	doc := json.RawMessage(`{
+		"_id": "post1",
+		"title": "Hello",
+		"views": 10,
+		"author": {"name": "Jane"},
+		"tags": ["a", "b", "c"],
+		"body": [{"_key": "k1", "text": "one"}, {"_key": "k2", "text": "two"}]
+	}`)
+
+	for _, tc := range []struct {
+		desc      string
+		mutations []*api.MutationItem
+		expect    string
+	}{
+		{
+			"set and setIfMissing",
+			[]*api.MutationItem{
+				{Patch: &api.Patch{ID: "post1", Set: map[string]*json.RawMessage{
+					"title":        mustJSONMsg("Bonjour"),
+					"author.bio":   mustJSONMsg("Writer"),
+					"seo.keywords": mustJSONMsg([]string{"x"}),
+					"tags[-1]":     mustJSONMsg("z"),
+				}}},
+				{Patch: &api.Patch{ID: "post1", SetIfMissing: map[string]*json.RawMessage{
+					"title":    mustJSONMsg("ignored"),
+					"subtitle": mustJSONMsg("Sub"),
+				}}},
+			},
+			`{
+				"_id": "post1",
+				"title": "Bonjour",
+				"subtitle": "Sub",
+				"views": 10,
+				"author": {"name": "Jane", "bio": "Writer"},
+				"seo": {"keywords": ["x"]},
+				"tags": ["a", "b", "z"],
+				"body": [{"_key": "k1", "text": "one"}, {"_key": "k2", "text": "two"}]
+			}`,
+		},
+		{
+			"set before setIfMissing within a patch",
+			[]*api.MutationItem{
+				{Patch: &api.Patch{
+					ID:           "post1",
+					Set:          map[string]*json.RawMessage{"seo": mustJSONMsg(map[string]string{"title": "T"})},
+					SetIfMissing: map[string]*json.RawMessage{"seo.description": mustJSONMsg("D")},
+				}},
+			},
+			`{
+				"_id": "post1",
+				"title": "Hello",
+				"views": 10,
+				"author": {"name": "Jane"},
+				"seo": {"title": "T", "description": "D"},
+				"tags": ["a", "b", "c"],
+				"body": [{"_key": "k1", "text": "one"}, {"_key": "k2", "text": "two"}]
+			}`,
+		},
+		{
+			"unset fields and array elements",
+			[]*api.MutationItem{
+				{Patch: &api.Patch{ID: "post1", Unset: []string{"author.name", "tags[0]", `body[_key=="k2"]`, "missing.path"}}},
+			},
+			`{
+				"_id": "post1",
+				"title": "Hello",
+				"views": 10,
+				"author": {},
+				"tags": ["b", "c"],
+				"body": [{"_key": "k1", "text": "one"}]
+			}`,
+		},
+		{
+			"inc and dec",
+			[]*api.MutationItem{
+				{Patch: &api.Patch{ID: "post1", Inc: map[string]float64{"views": 5, "missing": 1}}},
+				{Patch: &api.Patch{ID: "post1", Dec: map[string]float64{"views": 0.5}}},
+			},
+			`{
+				"_id": "post1",
+				"title": "Hello",
+				"views": 14.5,
+				"author": {"name": "Jane"},
+				"tags": ["a", "b", "c"],
+				"body": [{"_key": "k1", "text": "one"}, {"_key": "k2", "text": "two"}]
+			}`,
+		},
+		{
+			"insert before, after and replace",
+			[]*api.MutationItem{
+				{Patch: &api.Patch{ID: "post1", Insert: &api.Insert{
+					Before: "tags[0]", Items: []*json.RawMessage{mustJSONMsg("first")},
+				}}},
+				{Patch: &api.Patch{ID: "post1", Insert: &api.Insert{
+					After: "tags[-1]", Items: []*json.RawMessage{mustJSONMsg("last")},
+				}}},
+				{Patch: &api.Patch{ID: "post1", Insert: &api.Insert{
+					Replace: `body[_key=="k1"]`,
+					Items:   []*json.RawMessage{mustJSONMsg(map[string]string{"_key": "k3", "text": "three"})},
+				}}},
+				{Patch: &api.Patch{ID: "post1", Insert: &api.Insert{
+					After: `body[_key=='k3']`,
+					Items: []*json.RawMessage{mustJSONMsg(map[string]string{"_key": "k4", "text": "four"})},
+				}}},
+			},
+			`{
+				"_id": "post1",
+				"title": "Hello",
+				"views": 10,
+				"author": {"name": "Jane"},
+				"tags": ["first", "a", "b", "c", "last"],
+				"body": [
+					{"_key": "k3", "text": "three"},
+					{"_key": "k4", "text": "four"},
+					{"_key": "k2", "text": "two"}
+				]
+			}`,
+		},
+		{
+			"ignores other documents",
+			[]*api.MutationItem{
+				{Patch: &api.Patch{ID: "post2", Set: map[string]*json.RawMessage{"title": mustJSONMsg("x")}}},
+				{Delete: &api.Delete{ID: "post2"}},
+			},
+			string(doc),
+		},
+		{
+			"replaces document",
+			[]*api.MutationItem{
+				{CreateOrReplace: mustJSONMsg(map[string]string{"_id": "post1", "title": "New"})},
+				{CreateIfNotExists: mustJSONMsg(map[string]string{"_id": "post1", "title": "Ignored"})},
+			},
+			`{"_id": "post1", "title": "New"}`,
+		},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			result, err := sanity.ApplyMutations(doc, tc.mutations...)
+			require.NoError(t, err)
+			assert.JSONEq(t, tc.expect, string(result))
+		})
+	}
+
+	t.Run("delete", func(t *testing.T) {
+		result, err := sanity.ApplyMutations(doc, &api.MutationItem{Delete: &api.Delete{ID: "post1"}})
+		require.NoError(t, err)
+		assert.Nil(t, result)
+	})
+
+	t.Run("create from nothing", func(t *testing.T) {
+		result, err := sanity.ApplyMutations(nil,
+			&api.MutationItem{Create: mustJSONMsg(map[string]interface{}{"_id": "post1", "n": 1})},
+			&api.MutationItem{Patch: &api.Patch{ID: "post1", Inc: map[string]float64{"n": 1}}},
+		)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"_id": "post1", "n": 2}`, string(result))
+	})
+
+	t.Run("preserves large integers", func(t *testing.T) {
+		result, err := sanity.ApplyMutations(json.RawMessage(`{"_id":"a","big":9007199254740993}`),
+			&api.MutationItem{Patch: &api.Patch{ID: "a", Set: map[string]*json.RawMessage{"x": mustJSONMsg(1)}}},
+		)
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"_id":"a","big":9007199254740993,"x":1}`, string(result))
+	})
+
+	for _, tc := range []struct {
+		desc     string
+		mutation *api.MutationItem
+	}{
+		{"query patch", &api.MutationItem{Patch: &api.Patch{Query: "*", Unset: []string{"a"}}}},
+		{"query delete", &api.MutationItem{Delete: &api.Delete{Query: "*"}}},
+		{"diffMatchPatch", &api.MutationItem{Patch: &api.Patch{ID: "post1", DiffMatchPatch: map[string]string{"title": "@@"}}}},
+		{"wildcard path", &api.MutationItem{Patch: &api.Patch{ID: "post1", Unset: []string{"tags[*]"}}}},
+		{"insert into non-array path", &api.MutationItem{Patch: &api.Patch{ID: "post1", Insert: &api.Insert{
+			After: "title", Items: []*json.RawMessage{mustJSONMsg(1)},
+		}}}},
+	} {
+		tc := tc
+		t.Run("rejects "+tc.desc, func(t *testing.T) {
+			_, err := sanity.ApplyMutations(doc, tc.mutation)
+			require.Error(t, err)
+		})
+	}
+}
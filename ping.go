@@ -0,0 +1,74 @@
+package sanity
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// PingReason is the reason a ping failed.
+type PingReason int
+
+const (
+	// PingUnreachable means that no response was received from the API, such as because of
+	// a network error or timeout.
+	PingUnreachable PingReason = iota + 1
+
+	// PingUnauthorized means that the API rejected the token, or that the token does not
+	// grant access to the dataset.
+	PingUnauthorized
+
+	// PingFailed means that the API responded with any other error, such as for a dataset
+	// that does not exist.
+	PingFailed
+)
+
+func (r PingReason) String() string {
+	switch r {
+	case PingUnreachable:
+		return "unreachable"
+	case PingUnauthorized:
+		return "unauthorized"
+	case PingFailed:
+		return "failed"
+	default:
+		return "unknown"
+	}
+}
+
+// PingError is returned by Ping. It wraps the error of the request, which is of type
+// *RequestError unless the reason is PingUnreachable.
+type PingError struct {
+	Reason PingReason
+	Err    error
+}
+
+// Error implements the error interface.
+func (e *PingError) Error() string {
+	return "ping " + e.Reason.String() + ": " + e.Err.Error()
+}
+
+// Unwrap returns the error of the request.
+func (e *PingError) Unwrap() error {
+	return e.Err
+}
+
+// Ping checks that the API can be reached and that the client has access to its dataset, by
+// making a query that returns no documents. It bypasses the CDN, so that the check is not
+// answered from a cache. This is meant for readiness probes at startup: on failure, the
+// error is of type *PingError, whose reason tells a bad token or dataset apart from network
+// problems.
+func (c *Client) Ping(ctx context.Context) error {
+	if _, err := c.storedQuery(`*[_type == "sanity.imageAsset"][0...0]`).Do(ctx); err != nil {
+		var reqErr *RequestError
+		switch {
+		case !errors.As(err, &reqErr):
+			return &PingError{Reason: PingUnreachable, Err: err}
+		case reqErr.StatusCode() == http.StatusUnauthorized, reqErr.StatusCode() == http.StatusForbidden:
+			return &PingError{Reason: PingUnauthorized, Err: err}
+		default:
+			return &PingError{Reason: PingFailed, Err: err}
+		}
+	}
+	return nil
+}
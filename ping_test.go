@@ -0,0 +1,72 @@
+package sanity_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sanity "github.com/sanity-io/client-go"
+	"github.com/sanity-io/client-go/api"
+)
+
+func TestPing(t *testing.T) {
+	t.Run("succeeds", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, `*[_type == "sanity.imageAsset"][0...0]`, r.URL.Query().Get("query"))
+				_, err := w.Write(mustJSONBytes(&api.QueryResponse{Result: mustJSONMsg([]string{})}))
+				assert.NoError(t, err)
+			})
+
+			require.NoError(t, s.client.Ping(context.Background()))
+		})
+	})
+
+	for _, tc := range []struct {
+		status int
+		want   sanity.PingReason
+	}{
+		{status: http.StatusUnauthorized, want: sanity.PingUnauthorized},
+		{status: http.StatusForbidden, want: sanity.PingUnauthorized},
+		{status: http.StatusNotFound, want: sanity.PingFailed},
+	} {
+		tc := tc
+		t.Run(http.StatusText(tc.status), func(t *testing.T) {
+			withSuite(t, func(s *Suite) {
+				s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(tc.status)
+					_, _ = w.Write([]byte(`{"error":{"description":"nope","type":"httpError"}}`))
+				})
+
+				err := s.client.Ping(context.Background())
+
+				var pingErr *sanity.PingError
+				require.True(t, errors.As(err, &pingErr))
+				assert.Equal(t, tc.want, pingErr.Reason)
+
+				status, ok := sanity.StatusCode(err)
+				assert.True(t, ok)
+				assert.Equal(t, tc.status, status)
+			}, sanity.WithToken("bad"))
+		})
+	}
+
+	t.Run("network error", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.server.Close()
+
+			err := s.client.Ping(context.Background())
+
+			var pingErr *sanity.PingError
+			require.True(t, errors.As(err, &pingErr))
+			assert.Equal(t, sanity.PingUnreachable, pingErr.Reason)
+
+			_, ok := sanity.StatusCode(err)
+			assert.False(t, ok)
+		})
+	})
+}
@@ -0,0 +1,119 @@
+package sanity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/sanity-io/client-go/internal/requests"
+)
+
+// Dataset describes a dataset of the project.
+type Dataset struct {
+	// Name is the name of the dataset.
+	Name string `json:"name"`
+
+	// ACLMode is the access control mode of the dataset, such as "public" or "private".
+	ACLMode string `json:"aclMode"`
+}
+
+// ListDatasets returns the datasets of the client's project. Unlike the other methods, this
+// uses the projects API on the global API host, api.sanity.io, rather than the project's own
+// host, with the same token and retry behavior. If the host is set with WithHTTPHost, that
+// host is used instead.
+// On API request failure, this will return an error of type *RequestError.
+func (c *Client) ListDatasets(ctx context.Context) ([]Dataset, error) {
+	req := c.newGlobalRequest().
+		AppendPath("projects", c.projectID, "datasets").
+		Tag("", c.tag)
+
+	var datasets []Dataset
+	if _, err := c.do(ctx, req, &datasets); err != nil {
+		return nil, fmt.Errorf("listing datasets: %w", err)
+	}
+	return datasets, nil
+}
+
+// CreateDataset creates a dataset with the given name and access control mode, such as
+// "public" or "private". If aclMode is empty, the API default is used. If the dataset
+// already exists, an error of type *DatasetExistsError is returned.
+// On other API request failures, this will return an error of type *RequestError.
+func (c *Client) CreateDataset(ctx context.Context, name string, aclMode string) error {
+	if name == "" {
+		return errors.New("creating dataset: name must be set")
+	}
+
+	req := c.newAPIRequest().
+		Method(http.MethodPut).
+		AppendPath("datasets", name).
+		MarshalBody(&struct {
+			ACLMode string `json:"aclMode,omitempty"`
+		}{ACLMode: aclMode}).
+		Tag("", c.tag)
+
+	var resp struct{}
+	if _, err := c.do(ctx, req, &resp); err != nil {
+		var reqErr *RequestError
+		if errors.As(err, &reqErr) && reqErr.StatusCode() == http.StatusConflict {
+			err = &DatasetExistsError{Name: name, RequestError: reqErr}
+		}
+		return fmt.Errorf("creating dataset %q: %w", name, err)
+	}
+	return nil
+}
+
+// DeleteDataset deletes the dataset with the given name, along with all its documents. This
+// cannot be undone.
+// On API request failure, this will return an error of type *RequestError.
+func (c *Client) DeleteDataset(ctx context.Context, name string) error {
+	if name == "" {
+		return errors.New("deleting dataset: name must be set")
+	}
+
+	req := c.newAPIRequest().
+		Method(http.MethodDelete).
+		AppendPath("datasets", name).
+		Tag("", c.tag)
+
+	var resp struct{}
+	if _, err := c.do(ctx, req, &resp); err != nil {
+		return fmt.Errorf("deleting dataset %q: %w", name, err)
+	}
+	return nil
+}
+
+// DatasetExistsError is returned by CreateDataset when a dataset with the name already
+// exists. It wraps the *RequestError of the response.
+type DatasetExistsError struct {
+	// Name is the name of the dataset.
+	Name string
+
+	*RequestError
+}
+
+// Error implements the error interface.
+func (e *DatasetExistsError) Error() string {
+	return fmt.Sprintf("dataset %q already exists: %s", e.Name, e.RequestError.Error())
+}
+
+// Unwrap returns the underlying *RequestError.
+func (e *DatasetExistsError) Unwrap() error {
+	return e.RequestError
+}
+
+// newGlobalRequest returns a request to the global API host, for APIs that are not specific
+// to the project's host, such as the projects API.
+func (c *Client) newGlobalRequest() *requests.Request {
+	u := c.baseAPIURL
+	if u.Host == c.projectID+"."+APIHost {
+		u.Host = APIHost
+	}
+
+	r := requests.New(u).CleanPath()
+	c.setHeaders(r)
+	if c.gzipMinSize > 0 {
+		r.Gzip(c.gzipMinSize)
+	}
+	return r
+}
@@ -0,0 +1,158 @@
+package sanity_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sanity "github.com/sanity-io/client-go"
+)
+
+func TestListDatasets(t *testing.T) {
+	t.Run("lists datasets", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/projects/myProject/datasets", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+				_, err := w.Write([]byte(`[{"name":"production","aclMode":"public"},{"name":"staging","aclMode":"private"}]`))
+				assert.NoError(t, err)
+			})
+
+			datasets, err := s.client.ListDatasets(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, []sanity.Dataset{
+				{Name: "production", ACLMode: "public"},
+				{Name: "staging", ACLMode: "private"},
+			}, datasets)
+		}, sanity.WithToken("token"))
+	})
+
+	t.Run("uses the global API host", func(t *testing.T) {
+		mux := chi.NewRouter()
+		mux.Get("/v1/projects/myProject/datasets", func(w http.ResponseWriter, r *http.Request) {
+			_, err := w.Write([]byte(`[]`))
+			assert.NoError(t, err)
+		})
+		server := httptest.NewServer(mux)
+		defer server.Close()
+
+		transport := &redirectTransport{server: server}
+		c, err := sanity.VersionV1.NewClient("myProject", "myDataset",
+			sanity.WithHTTPClient(&http.Client{Transport: transport}))
+		require.NoError(t, err)
+
+		datasets, err := c.ListDatasets(context.Background())
+		require.NoError(t, err)
+		assert.Empty(t, datasets)
+		assert.Equal(t, []string{"api.sanity.io"}, transport.hosts)
+	})
+
+	t.Run("request error", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/projects/myProject/datasets", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusUnauthorized)
+			})
+
+			_, err := s.client.ListDatasets(context.Background())
+			require.Error(t, err)
+			code, ok := sanity.StatusCode(err)
+			assert.True(t, ok)
+			assert.Equal(t, http.StatusUnauthorized, code)
+		})
+	})
+}
+
+func TestCreateDataset(t *testing.T) {
+	t.Run("creates dataset", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Put("/v1/datasets/ci-123", func(w http.ResponseWriter, r *http.Request) {
+				b, err := ioutil.ReadAll(r.Body)
+				assert.NoError(t, err)
+				assert.JSONEq(t, `{"aclMode":"private"}`, string(b))
+
+				_, err = w.Write([]byte(`{"datasetName":"ci-123","aclMode":"private"}`))
+				assert.NoError(t, err)
+			})
+
+			require.NoError(t, s.client.CreateDataset(context.Background(), "ci-123", "private"))
+		})
+	})
+
+	t.Run("omits empty ACL mode", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Put("/v1/datasets/ci-123", func(w http.ResponseWriter, r *http.Request) {
+				b, err := ioutil.ReadAll(r.Body)
+				assert.NoError(t, err)
+				assert.JSONEq(t, `{}`, string(b))
+
+				_, err = w.Write([]byte(`{}`))
+				assert.NoError(t, err)
+			})
+
+			require.NoError(t, s.client.CreateDataset(context.Background(), "ci-123", ""))
+		})
+	})
+
+	t.Run("already exists", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Put("/v1/datasets/production", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusConflict)
+				_, err := w.Write([]byte(`{"error":"Conflict","message":"Dataset already exists"}`))
+				assert.NoError(t, err)
+			})
+
+			err := s.client.CreateDataset(context.Background(), "production", "public")
+			require.Error(t, err)
+
+			var existsErr *sanity.DatasetExistsError
+			require.True(t, errors.As(err, &existsErr))
+			assert.Equal(t, "production", existsErr.Name)
+
+			var reqErr *sanity.RequestError
+			require.True(t, errors.As(err, &reqErr))
+			assert.Equal(t, http.StatusConflict, reqErr.StatusCode())
+		})
+	})
+
+	t.Run("other errors", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Put("/v1/datasets/bad", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+			})
+
+			err := s.client.CreateDataset(context.Background(), "bad", "")
+			require.Error(t, err)
+
+			var existsErr *sanity.DatasetExistsError
+			assert.False(t, errors.As(err, &existsErr))
+		})
+	})
+
+	t.Run("requires name", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			require.Error(t, s.client.CreateDataset(context.Background(), "", "public"))
+		})
+	})
+}
+
+func TestDeleteDataset(t *testing.T) {
+	withSuite(t, func(s *Suite) {
+		var deleted []string
+		s.mux.Delete("/v1/datasets/{name}", func(w http.ResponseWriter, r *http.Request) {
+			deleted = append(deleted, chi.URLParam(r, "name"))
+			_, err := w.Write([]byte(`{"deleted":true}`))
+			assert.NoError(t, err)
+		})
+
+		require.NoError(t, s.client.DeleteDataset(context.Background(), "ci-123"))
+		assert.Equal(t, []string{"ci-123"}, deleted)
+
+		require.Error(t, s.client.DeleteDataset(context.Background(), ""))
+	})
+}
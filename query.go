@@ -3,9 +3,14 @@ package sanity
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"net/http"
 	"reflect"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/sanity-io/client-go/api"
@@ -24,34 +29,75 @@ type QueryResult struct {
 
 	// Result is the raw JSON of the query result.
 	Result *json.RawMessage
+
+	// Explain is the raw JSON of the query execution plan. It is only set if the query
+	// was performed with QueryBuilder.Explain.
+	Explain *json.RawMessage
+
+	// Query is the GROQ query as echoed by the server, which may differ from the query that
+	// was sent if the server normalized it. It is empty if the query was performed with
+	// QueryBuilder.ReturnQuery(false).
+	Query string
+
+	raw       *api.QueryResponse
+	useNumber bool
+}
+
+// RawResponse returns the complete response returned by the server.
+func (q *QueryResult) RawResponse() *api.QueryResponse {
+	return q.raw
 }
 
-// Unmarshal unmarshals the result into a Go value or struct. If there were no results, the
-// destination value is set to the zero value.
+// Unmarshal unmarshals the result into a Go value or struct. The destination must be a
+// non-nil pointer. If there were no results, the destination value is set to the zero value.
 func (q *QueryResult) Unmarshal(dest interface{}) error {
-	if q.Result == nil {
-		v := reflect.ValueOf(&dest)
-		if v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr {
-			i := reflect.Indirect(v)
-			i.Set(reflect.Zero(i.Type()))
+	v := reflect.ValueOf(dest)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		if q.Query != "" {
+			return fmt.Errorf("unmarshaling result of query %q: dest must be a non-nil pointer, got %T", q.Query, dest)
 		}
+		return fmt.Errorf("unmarshaling query result: dest must be a non-nil pointer, got %T", dest)
+	}
+
+	if q.Result == nil {
+		v.Elem().Set(reflect.Zero(v.Elem().Type()))
 		return nil
 	}
 
-	return json.Unmarshal([]byte(*q.Result), dest)
+	return unmarshalJSON([]byte(*q.Result), dest, q.useNumber)
 }
 
 // QueryBuilder is a builder for queries.
 type QueryBuilder struct {
-	c      *Client
-	query  string
-	params map[string]interface{}
-	tag    string
+	c           *Client
+	query       string
+	params      map[string]interface{}
+	rawParams   json.RawMessage
+	tag         string
+	perspective string
+	noCDN       bool
+	explain     bool
+	locale      bool
+	omitQuery   bool
+	fragments   []Fragment
+	dataset     string // overrides the client dataset if set
+	timeout     time.Duration
+	err         error
 }
 
 // Param adds a query parameter. For example, Param("foo", "bar") makes $foo usable inside the
 // query. The passed-in value must be serializable to a JSON primitive.
+//
+// A nil pointer is sent as null. The parameter is still defined, and a filter such as
+// published == $published then matches documents whose field is null or not set, rather than
+// every document. To express a three-state filter with a *bool, where nil matches any value,
+// check for null in the query: !defined($published) || published == $published.
 func (qb *QueryBuilder) Param(name string, val interface{}) *QueryBuilder {
+	if name == localeParam && qb.locale {
+		qb.setErr(fmt.Errorf("parameter %q is already set by Locale", name))
+		return qb
+	}
+
 	if qb.params == nil {
 		qb.params = make(map[string]interface{}, 10) // Small size
 	}
@@ -60,61 +106,361 @@ func (qb *QueryBuilder) Param(name string, val interface{}) *QueryBuilder {
 	return qb
 }
 
+const localeParam = "lang"
+
+// Locale sets the $lang parameter to the given language code, such as "en" or "nb-NO", for
+// use in queries on internationalized content. How documents are tied to a language depends
+// on the schema; with document-level internationalization the recommended filter is
+//
+//	*[_type == "post" && language == $lang]
+//
+// or, for older setups, __i18n_lang == $lang. Setting the lang parameter both with Locale
+// and with Param is an error.
+func (qb *QueryBuilder) Locale(lang string) *QueryBuilder {
+	if _, ok := qb.params[localeParam]; ok {
+		qb.setErr(fmt.Errorf("parameter %q is already set", localeParam))
+		return qb
+	}
+
+	qb.Param(localeParam, lang)
+	qb.locale = true
+	return qb
+}
+
+// ParamString adds a string query parameter, encoded as a JSON string literal. Invalid UTF-8
+// is replaced with the Unicode replacement character.
+func (qb *QueryBuilder) ParamString(name string, val string) *QueryBuilder {
+	return qb.Param(name, val)
+}
+
+// ParamInt adds an integer query parameter, encoded without a fractional part.
+func (qb *QueryBuilder) ParamInt(name string, val int64) *QueryBuilder {
+	return qb.paramLiteral(name, strconv.FormatInt(val, 10))
+}
+
+// ParamFloat adds a floating-point query parameter. Unlike Param, the value always carries a
+// fractional part or exponent, so 1.0 is sent as 1.0 rather than 1. NaN and infinite values
+// cannot be represented and cause the query to fail.
+func (qb *QueryBuilder) ParamFloat(name string, val float64) *QueryBuilder {
+	if math.IsNaN(val) || math.IsInf(val, 0) {
+		qb.setErr(fmt.Errorf("parameter %q: cannot encode %v", name, val))
+		return qb
+	}
+
+	s := strconv.FormatFloat(val, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return qb.paramLiteral(name, s)
+}
+
+// ParamTime adds a time query parameter, encoded as an RFC 3339 string in UTC with
+// fractional seconds as needed, such as "2021-03-25T12:00:00.5Z". This is the format
+// expected by GROQ's dateTime() function and used for _createdAt and _updatedAt.
+func (qb *QueryBuilder) ParamTime(name string, val time.Time) *QueryBuilder {
+	return qb.Param(name, val.UTC().Format(time.RFC3339Nano))
+}
+
+// ParamsJSON adds all parameters of a JSON object, such as {"type":"post","limit":10}, as
+// if each had been added with Param. This is useful when parameters come from an external
+// source already as JSON. When the query is sent as a POST request, the object is sent
+// verbatim as the params field, without being decoded and re-encoded. Parameters added with
+// Param take precedence over those in the object.
+func (qb *QueryBuilder) ParamsJSON(raw json.RawMessage) *QueryBuilder {
+	var params map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &params); err != nil || params == nil {
+		qb.setErr(errors.New("params must be a JSON object"))
+		return qb
+	}
+
+	qb.rawParams = raw
+	return qb
+}
+
+func (qb *QueryBuilder) paramLiteral(name, literal string) *QueryBuilder {
+	raw := json.RawMessage(literal)
+	return qb.Param(name, &raw)
+}
+
+// Perspective sets the perspective of the query, such as "published" to see only published
+// documents, or "previewDrafts" to see drafts in place of their published documents. It
+// overrides the client default set with WithPerspective.
+func (qb *QueryBuilder) Perspective(p string) *QueryBuilder {
+	qb.perspective = p
+	return qb
+}
+
+// Explain requests the query execution plan, which is returned in QueryResult.Explain.
+// This is useful for diagnosing slow queries.
+func (qb *QueryBuilder) Explain() *QueryBuilder {
+	qb.explain = true
+	return qb
+}
+
+// ReturnQuery sets whether the server echoes the query back in the response, which it does
+// by default. Disabling it saves bytes on large queries, but leaves QueryResult.Query empty.
+func (qb *QueryBuilder) ReturnQuery(enable bool) *QueryBuilder {
+	qb.omitQuery = !enable
+	return qb
+}
+
+// QueryParam is a named query parameter, as accepted by the query helper methods on Client.
+type QueryParam struct {
+	Name  string
+	Value interface{}
+}
+
+// Param returns a query parameter for use with the query helper methods on Client. For
+// example, Param("id", "123") makes $id usable inside the query.
+func Param(name string, val interface{}) QueryParam {
+	return QueryParam{Name: name, Value: val}
+}
+
+func (qb *QueryBuilder) withParams(params []QueryParam) *QueryBuilder {
+	for _, p := range params {
+		qb.Param(p.Name, p.Value)
+	}
+	return qb
+}
+
+// Tag sets the request tag, overriding the client default set with WithTag.
 func (qb *QueryBuilder) Tag(tag string) *QueryBuilder {
 	qb.tag = tag
 	return qb
 }
 
+// Timeout sets a timeout for the query, covering all retries, in place of the client default
+// set with WithTimeout. Like the default, it never extends a shorter deadline of the context
+// passed to the call.
+func (qb *QueryBuilder) Timeout(d time.Duration) *QueryBuilder {
+	qb.timeout = d
+	return qb
+}
+
+// withTimeout applies the timeout set with Timeout to ctx.
+func (qb *QueryBuilder) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if qb.timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, qb.timeout)
+}
+
 // Query performs the query. On API failure, this will return an error of type *RequestError.
 func (qb *QueryBuilder) Do(ctx context.Context) (*QueryResult, error) {
-	req, err := qb.buildGET()
+	result, _, err := qb.do(ctx, false)
+	return result, err
+}
+
+// DoWithResponse is like Do, but also returns the HTTP response, such as for reading the
+// rate limit headers. The response body has already been read, but is preserved, so it can
+// be read again. On API failure, this will return an error of type *RequestError.
+func (qb *QueryBuilder) DoWithResponse(ctx context.Context) (*QueryResult, *http.Response, error) {
+	return qb.do(ctx, true)
+}
+
+func (qb *QueryBuilder) do(ctx context.Context, preserve bool) (*QueryResult, *http.Response, error) {
+	req, err := qb.buildRequest()
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	if len(req.EncodeURL()) > maxGETRequestURLLength {
-		req, err = qb.buildPOST()
-		if err != nil {
-			return nil, err
-		}
-	}
+	ctx, cancel := qb.withTimeout(ctx)
+	defer cancel()
 
 	var resp api.QueryResponse
-	if _, err := qb.c.do(ctx, req, &resp); err != nil {
-		return nil, err
+	var httpResp *http.Response
+	if preserve {
+		httpResp, err = qb.c.doPreserved(ctx, req, &resp)
+	} else {
+		httpResp, err = qb.c.do(ctx, req, &resp)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	raw := resp
+	for _, transform := range qb.c.transformers {
+		if resp.Result, err = transform(resp.Result); err != nil {
+			return nil, nil, fmt.Errorf("transforming result: %w", err)
+		}
 	}
 
 	result := &QueryResult{
-		Time:   time.Duration(resp.Ms) * time.Millisecond,
-		Result: resp.Result,
+		Time:      time.Duration(resp.Ms) * time.Millisecond,
+		Result:    resp.Result,
+		Explain:   resp.Explain,
+		Query:     resp.Query,
+		raw:       &raw,
+		useNumber: qb.c.useNumber,
 	}
 
 	if qb.c.callbacks.OnQueryResult != nil {
 		qb.c.callbacks.OnQueryResult(result)
 	}
 
-	return result, nil
+	return result, httpResp, nil
+}
+
+// DoRaw performs the query and copies the raw response body to w as it is received,
+// without decoding it. This is useful for proxying the API response verbatim. On API
+// failure, this will return an error of type *RequestError, and nothing is written to w.
+func (qb *QueryBuilder) DoRaw(ctx context.Context, w io.Writer) (*http.Response, error) {
+	req, err := qb.buildRequest()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancelQuery := qb.withTimeout(ctx)
+	defer cancelQuery()
+	ctx, cancel := qb.c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := qb.c.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return resp, fmt.Errorf("copying response body: %w", err)
+	}
+
+	return resp, nil
+}
+
+func (qb *QueryBuilder) setErr(err error) {
+	if qb.err == nil {
+		qb.err = err
+	}
+}
+
+func (qb *QueryBuilder) buildRequest() (*requests.Request, error) {
+	if qb.err != nil {
+		return nil, fmt.Errorf("query builder: %w", qb.err)
+	}
+
+	req, err := qb.buildGET()
+	if err != nil {
+		return nil, err
+	}
+
+	if length, max := len(req.EncodeURL()), qb.c.maxGETLength(); length > max {
+		switch qb.c.exceedPolicy {
+		case ExceedError:
+			return nil, &QueryLengthError{Length: length, Max: max}
+		case ExceedTruncate:
+			query := qb.composedQuery()
+			if len(query) > max {
+				query = strings.ToValidUTF8(query[:max], "")
+			}
+			return nil, &QueryLengthError{Length: length, Max: max, Query: query}
+		}
+		return qb.buildPOST()
+	}
+
+	return req, nil
 }
 
 func (qb *QueryBuilder) buildGET() (*requests.Request, error) {
-	req := qb.c.newQueryRequest().
-		AppendPath("data/query", qb.c.dataset).
-		Param("query", qb.query).
+	req := qb.newRequest().
+		AppendPath("data/query", qb.targetDataset()).
+		Param("query", qb.composedQuery()).
 		Tag(qb.tag, qb.c.tag)
-	for p, v := range qb.params {
-		b, err := json.Marshal(v)
+	qb.setQueryParams(req)
+	params, err := qb.marshalParams()
+	if err != nil {
+		return nil, err
+	}
+	for p, b := range params {
+		req.Param("$"+p, string(*b))
+	}
+	return req, nil
+}
+
+func (qb *QueryBuilder) buildPOST() (*requests.Request, error) {
+	var request interface{}
+	if len(qb.rawParams) > 0 && len(qb.params) == 0 {
+		request = &rawQueryRequest{Query: qb.composedQuery(), Params: qb.rawParams}
+	} else {
+		params, err := qb.marshalParams()
 		if err != nil {
-			return nil, fmt.Errorf("marshaling parameter %q to JSON: %w", p, err)
+			return nil, err
 		}
-		req.Param("$"+p, string(b))
+		request = &api.QueryRequest{Query: qb.composedQuery(), Params: params}
 	}
+
+	req := qb.newRequest().
+		Method(http.MethodPost).
+		AppendPath("data/query", qb.targetDataset()).
+		MarshalBody(request).
+		Tag(qb.tag, qb.c.tag)
+	qb.setQueryParams(req)
 	return req, nil
 }
 
-func (qb *QueryBuilder) buildPOST() (*requests.Request, error) {
-	request := &api.QueryRequest{
-		Query:  qb.query,
-		Params: make(map[string]*json.RawMessage, len(qb.params)),
+func (qb *QueryBuilder) targetDataset() string {
+	if qb.dataset != "" {
+		return qb.dataset
+	}
+	return qb.c.dataset
+}
+
+// newRequest returns a request to the query host, or to the API host if the query must not
+// go through the CDN.
+// storedQuery returns a builder for a query that reads documents as they are stored, for
+// reads that mutations depend on or that check their effect. It is sent to the API rather
+// than the CDN, so that the result is not stale, and with the raw perspective if the client
+// sets another one, so that a draft and its published document are each seen under their
+// own ID rather than one standing in for the other.
+func (c *Client) storedQuery(query string) *QueryBuilder {
+	qb := c.Query(query)
+	qb.noCDN = true
+	if c.perspective != "" {
+		qb.Perspective("raw")
+	}
+	return qb
+}
+
+func (qb *QueryBuilder) newRequest() *requests.Request {
+	if qb.noCDN {
+		return qb.c.forDataset(qb.c.newAPIRequest(), qb.targetDataset())
+	}
+	return qb.c.forDataset(qb.c.newQueryRequest(), qb.targetDataset())
+}
+
+// setQueryParams sets the URL parameters that are sent with both GET and POST requests.
+func (qb *QueryBuilder) setQueryParams(req *requests.Request) {
+	if qb.explain {
+		req.Param("explain", true)
+	}
+	if qb.omitQuery {
+		req.Param("returnQuery", false)
+	}
+	if p := qb.perspective; p != "" {
+		req.Param("perspective", p)
+	} else if qb.c.perspective != "" {
+		req.Param("perspective", qb.c.perspective)
+	}
+}
+
+// marshalParams returns the JSON encoding of every parameter, including those added with
+// ParamsJSON.
+func (qb *QueryBuilder) marshalParams() (map[string]*json.RawMessage, error) {
+	params := make(map[string]*json.RawMessage, len(qb.params))
+	if len(qb.rawParams) > 0 {
+		// Decoding into json.RawMessage values keeps a null as the literal null, where a
+		// *json.RawMessage would be left nil.
+		var raw map[string]json.RawMessage
+		if err := json.Unmarshal(qb.rawParams, &raw); err != nil {
+			return nil, fmt.Errorf("decoding parameters: %w", err)
+		}
+		for p, v := range raw {
+			v := v
+			params[p] = &v
+		}
 	}
 
 	for p, v := range qb.params {
@@ -122,12 +468,13 @@ func (qb *QueryBuilder) buildPOST() (*requests.Request, error) {
 		if err != nil {
 			return nil, fmt.Errorf("marshaling parameter %q to JSON: %w", p, err)
 		}
-		request.Params[p] = (*json.RawMessage)(&b)
+		params[p] = (*json.RawMessage)(&b)
 	}
+	return params, nil
+}
 
-	return qb.c.newQueryRequest().
-		Method(http.MethodPost).
-		AppendPath("data/query", qb.c.dataset).
-		MarshalBody(request).
-		Tag(qb.tag, qb.c.tag), nil
+// rawQueryRequest is a query request whose params are sent verbatim.
+type rawQueryRequest struct {
+	Query  string          `json:"query"`
+	Params json.RawMessage `json:"params"`
 }
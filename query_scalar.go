@@ -0,0 +1,133 @@
+package sanity
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrNullResult is returned by the scalar query helpers when the query result is null.
+var ErrNullResult = errors.New("query result is null")
+
+// QueryScalar performs a query that is expected to return a single value, such as
+// count(*[_type == "post"]), and returns its raw JSON. If the result is null, ErrNullResult
+// is returned. On API failure, this will return an error of type *RequestError.
+func (c *Client) QueryScalar(ctx context.Context, query string, params ...QueryParam) (*json.RawMessage, error) {
+	result, err := c.Query(query).withParams(params).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if result.Result == nil || bytes.Equal(bytes.TrimSpace(*result.Result), []byte("null")) {
+		return nil, ErrNullResult
+	}
+
+	return result.Result, nil
+}
+
+// QueryInt performs a query that is expected to return a single integer.
+func (c *Client) QueryInt(ctx context.Context, query string, params ...QueryParam) (int64, error) {
+	v, err := c.queryScalarValue(ctx, query, params)
+	if err != nil {
+		return 0, err
+	}
+
+	n, ok := v.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("expected query result to be a number, got %s", scalarTypeName(v))
+	}
+
+	i, err := n.Int64()
+	if err != nil {
+		return 0, fmt.Errorf("expected query result to be an integer, got %s", n)
+	}
+	return i, nil
+}
+
+// QueryFloat performs a query that is expected to return a single number.
+func (c *Client) QueryFloat(ctx context.Context, query string, params ...QueryParam) (float64, error) {
+	v, err := c.queryScalarValue(ctx, query, params)
+	if err != nil {
+		return 0, err
+	}
+
+	n, ok := v.(json.Number)
+	if !ok {
+		return 0, fmt.Errorf("expected query result to be a number, got %s", scalarTypeName(v))
+	}
+
+	f, err := n.Float64()
+	if err != nil {
+		return 0, fmt.Errorf("parsing number %s: %w", n, err)
+	}
+	return f, nil
+}
+
+// QueryString performs a query that is expected to return a single string.
+func (c *Client) QueryString(ctx context.Context, query string, params ...QueryParam) (string, error) {
+	v, err := c.queryScalarValue(ctx, query, params)
+	if err != nil {
+		return "", err
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("expected query result to be a string, got %s", scalarTypeName(v))
+	}
+	return s, nil
+}
+
+// QueryBool performs a query that is expected to return a single boolean.
+func (c *Client) QueryBool(ctx context.Context, query string, params ...QueryParam) (bool, error) {
+	v, err := c.queryScalarValue(ctx, query, params)
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("expected query result to be a boolean, got %s", scalarTypeName(v))
+	}
+	return b, nil
+}
+
+// Count returns the number of documents matching the filter, such as _type == $type, by
+// performing count(*[filter]).
+func (c *Client) Count(ctx context.Context, filter string, params ...QueryParam) (int64, error) {
+	return c.QueryInt(ctx, "count(*["+filter+"])", params...)
+}
+
+func (c *Client) queryScalarValue(ctx context.Context, query string, params []QueryParam) (interface{}, error) {
+	raw, err := c.QueryScalar(ctx, query, params...)
+	if err != nil {
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(*raw))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("decoding query result: %w", err)
+	}
+	return v, nil
+}
+
+func scalarTypeName(v interface{}) string {
+	switch v.(type) {
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
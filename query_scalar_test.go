@@ -0,0 +1,161 @@
+package sanity_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sanity "github.com/sanity-io/client-go"
+	"github.com/sanity-io/client-go/api"
+)
+
+func withScalarResult(t *testing.T, s *Suite, result interface{}) {
+	s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, `"post"`, r.URL.Query().Get("$type"))
+
+		w.WriteHeader(http.StatusOK)
+		_, err := w.Write(mustJSONBytes(&api.QueryResponse{
+			Result: mustJSONMsg(result),
+		}))
+		assert.NoError(t, err)
+	})
+}
+
+func TestQueryScalar(t *testing.T) {
+	query := "count(*[_type == $type])"
+	ctx := context.Background()
+	param := sanity.Param("type", "post")
+
+	t.Run("raw", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			withScalarResult(t, s, 42)
+			raw, err := s.client.QueryScalar(ctx, query, param)
+			require.NoError(t, err)
+			assert.Equal(t, "42", string(*raw))
+		})
+	})
+
+	t.Run("int", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			withScalarResult(t, s, int64(9007199254740993))
+			v, err := s.client.QueryInt(ctx, query, param)
+			require.NoError(t, err)
+			assert.Equal(t, int64(9007199254740993), v)
+		})
+	})
+
+	t.Run("int from float", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			withScalarResult(t, s, 1.5)
+			_, err := s.client.QueryInt(ctx, query, param)
+			require.Error(t, err)
+		})
+	})
+
+	t.Run("float", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			withScalarResult(t, s, 1.5)
+			v, err := s.client.QueryFloat(ctx, query, param)
+			require.NoError(t, err)
+			assert.Equal(t, 1.5, v)
+		})
+	})
+
+	t.Run("string", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			withScalarResult(t, s, "hello")
+			v, err := s.client.QueryString(ctx, query, param)
+			require.NoError(t, err)
+			assert.Equal(t, "hello", v)
+		})
+	})
+
+	t.Run("bool", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			withScalarResult(t, s, true)
+			v, err := s.client.QueryBool(ctx, query, param)
+			require.NoError(t, err)
+			assert.True(t, v)
+		})
+	})
+
+	t.Run("null", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			withScalarResult(t, s, nil)
+			_, err := s.client.QueryString(ctx, query, param)
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, sanity.ErrNullResult))
+		})
+	})
+
+	t.Run("type mismatch", func(t *testing.T) {
+		for _, tc := range []struct {
+			desc string
+			do   func(s *Suite) error
+		}{
+			{"int", func(s *Suite) error { _, err := s.client.QueryInt(ctx, query, param); return err }},
+			{"float", func(s *Suite) error { _, err := s.client.QueryFloat(ctx, query, param); return err }},
+			{"bool", func(s *Suite) error { _, err := s.client.QueryBool(ctx, query, param); return err }},
+		} {
+			t.Run(tc.desc, func(t *testing.T) {
+				withSuite(t, func(s *Suite) {
+					withScalarResult(t, s, "hello")
+					err := tc.do(s)
+					require.Error(t, err)
+					assert.Contains(t, err.Error(), "got string")
+				})
+			})
+		}
+
+		withSuite(t, func(s *Suite) {
+			withScalarResult(t, s, []int{1})
+			_, err := s.client.QueryString(ctx, query, param)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "got array")
+		})
+	})
+}
+
+func TestCount(t *testing.T) {
+	ctx := context.Background()
+	param := sanity.Param("type", "post")
+
+	for _, tc := range []struct {
+		desc  string
+		count int64
+	}{
+		{"zero", 0},
+		{"small", 3},
+		{"large", 5000000000},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			withSuite(t, func(s *Suite) {
+				s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+					assert.Equal(t, "count(*[_type == $type])", r.URL.Query().Get("query"))
+					assert.Equal(t, `"post"`, r.URL.Query().Get("$type"))
+
+					w.WriteHeader(http.StatusOK)
+					_, err := w.Write(mustJSONBytes(&api.QueryResponse{Result: mustJSONMsg(tc.count)}))
+					assert.NoError(t, err)
+				})
+
+				n, err := s.client.Count(ctx, "_type == $type", param)
+				require.NoError(t, err)
+				assert.Equal(t, tc.count, n)
+			})
+		})
+	}
+
+	t.Run("non-numeric result", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			withScalarResult(t, s, "many")
+			_, err := s.client.Count(ctx, "_type == $type", param)
+			require.Error(t, err)
+		})
+	})
+}
@@ -0,0 +1,177 @@
+package sanity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+)
+
+// Stream returns an iterator over the elements of the query result, which must be an array.
+// Elements are decoded from the response as it is received, so that the whole result never
+// has to be held in memory. The query is performed on the first call to Next, and the
+// stream must be closed when done.
+func (qb *QueryBuilder) Stream(ctx context.Context) *QueryStream {
+	return &QueryStream{qb: qb, ctx: ctx}
+}
+
+// StreamEach performs the query and calls fn with each element of the result, which must be
+// an array, as it is decoded from the response. It is shorthand for Stream(ctx).ForEach(fn).
+// On API failure, this will return an error of type *RequestError.
+func (qb *QueryBuilder) StreamEach(ctx context.Context, fn func(json.RawMessage) error) error {
+	return qb.Stream(ctx).ForEach(fn)
+}
+
+// QueryStream is an iterator over the elements of a query result. It is not safe for
+// concurrent use.
+type QueryStream struct {
+	qb     *QueryBuilder
+	ctx    context.Context
+	buffer int
+
+	started bool
+	err     error
+	cancel  context.CancelFunc
+	body    io.Closer
+	stream  *arrayStream
+	items   chan streamItem
+	wg      sync.WaitGroup
+}
+
+type streamItem struct {
+	raw json.RawMessage
+	err error
+}
+
+// WithBuffer makes the stream decode up to n elements ahead of the consumer in the
+// background. This smooths throughput when the consumer processes elements more slowly than
+// the network delivers them. It must be called before the first call to Next.
+func (s *QueryStream) WithBuffer(n int) *QueryStream {
+	s.buffer = n
+	return s
+}
+
+// Next returns the next element of the result. When there are no more elements, io.EOF is
+// returned. On API failure, this will return an error of type *RequestError.
+func (s *QueryStream) Next() (json.RawMessage, error) {
+	if !s.started {
+		s.started = true
+		s.err = s.start()
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+
+	var item streamItem
+	if s.items != nil {
+		var ok bool
+		if item, ok = <-s.items; !ok {
+			item.err = io.EOF
+		}
+	} else {
+		item.raw, item.err = s.stream.next()
+	}
+
+	if item.err != nil {
+		s.err = item.err
+		if !errors.Is(item.err, io.EOF) && s.ctx.Err() != nil {
+			s.err = s.ctx.Err()
+		}
+		return nil, s.err
+	}
+	return item.raw, nil
+}
+
+// ForEach calls fn with each element of the result, in order, and closes the stream. If fn
+// returns an error, the stream is stopped and the error is returned. On API failure, this
+// will return an error of type *RequestError.
+func (s *QueryStream) ForEach(fn func(json.RawMessage) error) error {
+	defer func() {
+		_ = s.Close()
+	}()
+
+	for {
+		raw, err := s.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		if err := fn(raw); err != nil {
+			return err
+		}
+	}
+}
+
+// Close stops the stream and releases its resources, waiting for any background decoding
+// to finish. It is safe to call Close more than once.
+func (s *QueryStream) Close() error {
+	if !s.started {
+		s.started = true
+		s.err = errors.New("stream is closed")
+		return nil
+	}
+	if s.cancel == nil {
+		return nil
+	}
+
+	s.cancel()
+	err := s.body.Close()
+	s.wg.Wait()
+	s.cancel, s.err = nil, errors.New("stream is closed")
+	return err
+}
+
+func (s *QueryStream) start() error {
+	req, err := s.qb.buildRequest()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancelQuery := s.qb.withTimeout(s.ctx)
+	ctx, cancel := s.qb.c.withTimeout(ctx)
+	ctx, cancelStream := context.WithCancel(ctx)
+	s.ctx = ctx
+	s.cancel = func() {
+		cancelStream()
+		cancel()
+		cancelQuery()
+	}
+
+	resp, err := s.qb.c.send(ctx, req)
+	if err != nil {
+		s.cancel()
+		s.cancel = nil
+		return err
+	}
+
+	s.body = resp.Body
+	s.stream = newArrayStream(resp.Body, "result")
+	if s.buffer > 0 {
+		s.items = make(chan streamItem, s.buffer)
+		s.wg.Add(1)
+		go s.prefetch(ctx)
+	}
+	return nil
+}
+
+// prefetch decodes elements into the buffer until the result ends, an error occurs or the
+// stream is closed.
+func (s *QueryStream) prefetch(ctx context.Context) {
+	defer s.wg.Done()
+	defer close(s.items)
+
+	for {
+		raw, err := s.stream.next()
+		select {
+		case s.items <- streamItem{raw: raw, err: err}:
+		case <-ctx.Done():
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
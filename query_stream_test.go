@@ -0,0 +1,213 @@
+package sanity_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sanity "github.com/sanity-io/client-go"
+)
+
+// serveStream serves a query result of n elements, each padded to about size bytes,
+// flushing and pausing for delay after every chunk elements.
+func serveStream(t testing.TB, s *Suite, n, size, chunk int, delay time.Duration) {
+	pad := strings.Repeat("x", size)
+	s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+		flusher := w.(http.Flusher)
+		if _, err := w.Write([]byte(`{"ms":1,"result":[`)); err != nil {
+			return
+		}
+		for i := 0; i < n; i++ {
+			sep := ","
+			if i == 0 {
+				sep = ""
+			}
+			if _, err := fmt.Fprintf(w, `%s{"_id":"doc%d","pad":%q}`, sep, i, pad); err != nil {
+				return
+			}
+			if (i+1)%chunk == 0 {
+				flusher.Flush()
+				select {
+				case <-time.After(delay):
+				case <-r.Context().Done():
+					return
+				}
+			}
+		}
+		_, _ = w.Write([]byte(`]}`))
+	})
+}
+
+func TestQuery_Stream(t *testing.T) {
+	for _, buffer := range []int{0, 1, 16} {
+		buffer := buffer
+		t.Run(fmt.Sprintf("buffer %d", buffer), func(t *testing.T) {
+			withSuite(t, func(s *Suite) {
+				serveStream(t, s, 250, 10, 100, 0)
+
+				stream := s.client.Query("*").Stream(context.Background()).WithBuffer(buffer)
+				defer stream.Close()
+
+				var ids []string
+				for {
+					raw, err := stream.Next()
+					if err == io.EOF {
+						break
+					}
+					require.NoError(t, err)
+
+					var doc struct {
+						ID string `json:"_id"`
+					}
+					require.NoError(t, json.Unmarshal(raw, &doc))
+					ids = append(ids, doc.ID)
+				}
+
+				require.Len(t, ids, 250)
+				for i, id := range ids {
+					assert.Equal(t, fmt.Sprintf("doc%d", i), id)
+				}
+
+				_, err := stream.Next()
+				assert.Equal(t, io.EOF, err)
+			})
+		})
+	}
+
+	t.Run("close stops buffered stream", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			serveStream(t, s, 1000000, 10, 10, time.Millisecond)
+
+			stream := s.client.Query("*").Stream(context.Background()).WithBuffer(4)
+			for i := 0; i < 20; i++ {
+				_, err := stream.Next()
+				require.NoError(t, err)
+			}
+
+			done := make(chan struct{})
+			go func() {
+				_ = stream.Close()
+				close(done)
+			}()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("Close did not return")
+			}
+
+			_, err := stream.Next()
+			require.Error(t, err)
+		})
+	})
+
+	t.Run("non-array result", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				_, err := w.Write([]byte(`{"result":{"_id":"doc1"}}`))
+				assert.NoError(t, err)
+			})
+
+			stream := s.client.Query("*[0]").Stream(context.Background())
+			defer stream.Close()
+
+			_, err := stream.Next()
+			require.Error(t, err)
+			assert.NotEqual(t, io.EOF, err)
+		})
+	})
+}
+
+func TestQueryStream_ForEach(t *testing.T) {
+	t.Run("large result", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			serveStream(t, s, 20000, 100, 1000, 0)
+
+			var n int
+			err := s.client.Query("*").Stream(context.Background()).ForEach(func(raw json.RawMessage) error {
+				var doc struct {
+					ID string `json:"_id"`
+				}
+				if err := json.Unmarshal(raw, &doc); err != nil {
+					return err
+				}
+				assert.Equal(t, fmt.Sprintf("doc%d", n), doc.ID)
+				n++
+				return nil
+			})
+			require.NoError(t, err)
+			assert.Equal(t, 20000, n)
+		})
+	})
+
+	t.Run("stops on callback error", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			serveStream(t, s, 1000000, 10, 10, time.Millisecond)
+
+			stop := errors.New("stop")
+			var n int
+			err := s.client.Query("*").StreamEach(context.Background(), func(json.RawMessage) error {
+				if n++; n == 5 {
+					return stop
+				}
+				return nil
+			})
+			assert.Equal(t, stop, err)
+			assert.Equal(t, 5, n)
+		})
+	})
+
+	t.Run("request error", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+			})
+
+			err := s.client.Query("*").Stream(context.Background()).ForEach(func(json.RawMessage) error {
+				t.Fatal("unexpected element")
+				return nil
+			})
+			var reqErr *sanity.RequestError
+			assert.True(t, errors.As(err, &reqErr))
+		})
+	})
+}
+
+// BenchmarkQuery_Stream compares a consumer that blocks periodically, such as on writes to
+// another service, reading a large result that arrives in bursts, with and without a
+// prefetch buffer. With the buffer, decoding continues while the consumer is blocked.
+func BenchmarkQuery_Stream(b *testing.B) {
+	for _, buffer := range []int{0, 256} {
+		buffer := buffer
+		b.Run(fmt.Sprintf("buffer %d", buffer), func(b *testing.B) {
+			withSuite(b, func(s *Suite) {
+				serveStream(b, s, 1000, 10000, 100, 2*time.Millisecond)
+
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					stream := s.client.Query("*").Stream(context.Background()).WithBuffer(buffer)
+					for n := 0; ; {
+						if _, err := stream.Next(); err != nil {
+							if err != io.EOF {
+								b.Fatal(err)
+							}
+							break
+						}
+						if n++; n%10 == 0 {
+							time.Sleep(time.Millisecond)
+						}
+					}
+					_ = stream.Close()
+				}
+			})
+		})
+	}
+}
@@ -1,8 +1,12 @@
 package sanity_test
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math"
 	"net/http"
 	"strings"
 	"testing"
@@ -54,6 +58,28 @@ func TestQuery_basic(t *testing.T) {
 	})
 }
 
+func TestQuery_echoedQuery(t *testing.T) {
+	withSuite(t, func(s *Suite) {
+		s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+			_, err := w.Write(mustJSONBytes(&api.QueryResponse{
+				Ms:     3,
+				Query:  "*[_type == 'post'][0]",
+				Result: mustJSONMsg(1),
+			}))
+			assert.NoError(t, err)
+		})
+
+		result, err := s.client.Query("*[_type=='post'][0]").Do(context.Background())
+		require.NoError(t, err)
+
+		assert.Equal(t, "*[_type == 'post'][0]", result.Query)
+		require.NotNil(t, result.RawResponse())
+		assert.Equal(t, "*[_type == 'post'][0]", result.RawResponse().Query)
+		assert.Equal(t, float64(3), result.RawResponse().Ms)
+	})
+}
+
 func TestQuery_params(t *testing.T) {
 	groq := "*[0]"
 
@@ -186,3 +212,686 @@ func TestQuery_tag(t *testing.T) {
 		}, sanity.WithTag("default"))
 	})
 }
+
+func TestQuery_perspective(t *testing.T) {
+	for _, tc := range []struct {
+		desc       string
+		clientOpts []sanity.Option
+		query      string
+		expect     string
+	}{
+		{"unset", nil, "", ""},
+		{"client default", []sanity.Option{sanity.WithPerspective("published")}, "", "published"},
+		{"per query", nil, "previewDrafts", "previewDrafts"},
+		{"per query overrides default", []sanity.Option{sanity.WithPerspective("published")}, "raw", "raw"},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			for _, size := range []int{1, 1000} {
+				groq := "*[foo=='" + strings.Repeat("foo", size) + "']"
+
+				withSuite(t, func(s *Suite) {
+					s.mux.HandleFunc("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+						assert.Equal(t, tc.expect, r.URL.Query().Get("perspective"))
+
+						w.WriteHeader(http.StatusOK)
+						_, err := w.Write(mustJSONBytes(&api.QueryResponse{}))
+						assert.NoError(t, err)
+					})
+
+					qb := s.client.Query(groq)
+					if tc.query != "" {
+						qb.Perspective(tc.query)
+					}
+					_, err := qb.Do(context.Background())
+					require.NoError(t, err)
+				}, tc.clientOpts...)
+			}
+		})
+	}
+}
+
+func TestQuery_maxQueryLength(t *testing.T) {
+	groq := "*[foo=='" + strings.Repeat("foo", 100) + "']"
+
+	for _, tc := range []struct {
+		desc      string
+		opt       sanity.Option
+		wantPOST  bool
+		wantErr   bool
+		wantQuery string
+	}{
+		{"default", nil, false, false, ""},
+		{"POST", sanity.WithMaxQueryLength(200, sanity.ExceedPOST), true, false, ""},
+		{"Error", sanity.WithMaxQueryLength(200, sanity.ExceedError), false, true, ""},
+		{"Truncate", sanity.WithMaxQueryLength(200, sanity.ExceedTruncate), false, true, groq[:200]},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			var opts []sanity.Option
+			if tc.opt != nil {
+				opts = append(opts, tc.opt)
+			}
+
+			withSuite(t, func(s *Suite) {
+				var method string
+				s.mux.HandleFunc("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+					method = r.Method
+
+					w.WriteHeader(http.StatusOK)
+					_, err := w.Write(mustJSONBytes(&api.QueryResponse{}))
+					assert.NoError(t, err)
+				})
+
+				_, err := s.client.Query(groq).Do(context.Background())
+				if !tc.wantErr {
+					require.NoError(t, err)
+					if tc.wantPOST {
+						assert.Equal(t, http.MethodPost, method)
+					} else {
+						assert.Equal(t, http.MethodGet, method)
+					}
+					return
+				}
+
+				var lengthErr *sanity.QueryLengthError
+				require.True(t, errors.As(err, &lengthErr))
+				assert.Equal(t, 200, lengthErr.Max)
+				assert.Greater(t, lengthErr.Length, 200)
+				assert.Equal(t, tc.wantQuery, lengthErr.Query)
+				assert.Empty(t, method)
+			}, opts...)
+		})
+	}
+}
+
+func TestQuery_DoWithResponse(t *testing.T) {
+	withSuite(t, func(s *Suite) {
+		s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Sanity-Shard", "gcp-eu-w1-01")
+			w.Header().Set("X-RateLimit-Remaining-Second", "42")
+			_, err := w.Write([]byte(`{"ms":1,"result":{"title":"x"}}`))
+			assert.NoError(t, err)
+		})
+
+		result, resp, err := s.client.Query("*[0]").DoWithResponse(context.Background())
+		require.NoError(t, err)
+		require.NotNil(t, resp)
+		assert.Equal(t, "gcp-eu-w1-01", resp.Header.Get("X-Sanity-Shard"))
+		assert.Equal(t, "42", resp.Header.Get("X-RateLimit-Remaining-Second"))
+
+		var doc struct {
+			Title string `json:"title"`
+		}
+		require.NoError(t, result.Unmarshal(&doc))
+		assert.Equal(t, "x", doc.Title)
+
+		b, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		assert.Equal(t, `{"ms":1,"result":{"title":"x"}}`, string(b))
+		require.NoError(t, resp.Body.Close())
+	})
+}
+
+func TestQuery_Timeout(t *testing.T) {
+	slowHandler := func(s *Suite, delay time.Duration) {
+		s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(delay):
+			}
+			_, err := w.Write([]byte(`{"result":null}`))
+			assert.NoError(t, err)
+		})
+	}
+
+	t.Run("trips on slow query", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			slowHandler(s, time.Second)
+
+			start := time.Now()
+			_, err := s.client.Query("*").Timeout(20 * time.Millisecond).Do(context.Background())
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, context.DeadlineExceeded))
+			assert.True(t, time.Since(start) < 500*time.Millisecond)
+		}, sanity.WithTimeout(time.Minute))
+	})
+
+	t.Run("overrides client default", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			slowHandler(s, 50*time.Millisecond)
+
+			_, err := s.client.Query("*").Timeout(time.Second).Do(context.Background())
+			require.NoError(t, err)
+		}, sanity.WithTimeout(10*time.Millisecond))
+	})
+
+	t.Run("does not extend caller deadline", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			slowHandler(s, time.Second)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+			_, err := s.client.Query("*").Timeout(time.Minute).Do(ctx)
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, context.DeadlineExceeded))
+			assert.True(t, time.Since(start) < 500*time.Millisecond)
+		})
+	})
+
+	t.Run("applies to DoRaw", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			slowHandler(s, time.Second)
+
+			var buf bytes.Buffer
+			_, err := s.client.Query("*").Timeout(20*time.Millisecond).DoRaw(context.Background(), &buf)
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, context.DeadlineExceeded))
+		})
+	})
+}
+
+func TestQuery_DoRaw(t *testing.T) {
+	t.Run("writes raw response body", func(t *testing.T) {
+		body := `{"ms":12,"query":"*[0]","result":{"_id":"123"}}`
+
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+				_, err := w.Write([]byte(body))
+				assert.NoError(t, err)
+			})
+
+			var buf bytes.Buffer
+			resp, err := s.client.Query("*[0]").DoRaw(context.Background(), &buf)
+			require.NoError(t, err)
+
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, body, buf.String())
+		})
+	})
+
+	t.Run("returns request error without writing", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadRequest)
+				_, err := w.Write([]byte(`{"error":{"type":"queryParseError"}}`))
+				assert.NoError(t, err)
+			})
+
+			var buf bytes.Buffer
+			_, err := s.client.Query("*[").DoRaw(context.Background(), &buf)
+			require.Error(t, err)
+
+			var reqErr *sanity.RequestError
+			require.True(t, errors.As(err, &reqErr))
+			assert.Empty(t, buf.String())
+		})
+	})
+}
+
+func TestQuery_explain(t *testing.T) {
+	plan := map[string]interface{}{"type": "scan", "cost": 42}
+
+	for _, tc := range []struct {
+		desc   string
+		method string
+		groq   string
+	}{
+		{"GET", http.MethodGet, "*[_type == 'post']"},
+		{"POST", http.MethodPost, "*[foo=='" + strings.Repeat("foo", 1000) + "']"},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			withSuite(t, func(s *Suite) {
+				s.mux.MethodFunc(tc.method, "/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+					assert.Equal(t, "true", r.URL.Query().Get("explain"))
+
+					w.WriteHeader(http.StatusOK)
+					_, err := w.Write(mustJSONBytes(&api.QueryResponse{
+						Explain: mustJSONMsg(plan),
+					}))
+					assert.NoError(t, err)
+				})
+
+				result, err := s.client.Query(tc.groq).Explain().Do(context.Background())
+				require.NoError(t, err)
+				require.NotNil(t, result.Explain)
+				assert.JSONEq(t, string(mustJSONBytes(plan)), string(*result.Explain))
+			})
+		})
+	}
+
+	t.Run("not requested by default", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "", r.URL.Query().Get("explain"))
+
+				w.WriteHeader(http.StatusOK)
+				_, err := w.Write(mustJSONBytes(&api.QueryResponse{}))
+				assert.NoError(t, err)
+			})
+
+			result, err := s.client.Query("*").Do(context.Background())
+			require.NoError(t, err)
+			assert.Nil(t, result.Explain)
+		})
+	})
+}
+
+func TestQuery_ReturnQuery(t *testing.T) {
+	for _, tc := range []struct {
+		desc   string
+		method string
+		groq   string
+	}{
+		{"GET", http.MethodGet, "*[_type == 'post']"},
+		{"POST", http.MethodPost, "*[foo=='" + strings.Repeat("foo", 1000) + "']"},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			withSuite(t, func(s *Suite) {
+				s.mux.MethodFunc(tc.method, "/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+					assert.Equal(t, "false", r.URL.Query().Get("returnQuery"))
+
+					w.WriteHeader(http.StatusOK)
+					_, err := w.Write(mustJSONBytes(&api.QueryResponse{
+						Ms:     1,
+						Result: mustJSONMsg([]string{"a", "b"}),
+					}))
+					assert.NoError(t, err)
+				})
+
+				result, err := s.client.Query(tc.groq).ReturnQuery(false).Do(context.Background())
+				require.NoError(t, err)
+				assert.Empty(t, result.Query)
+
+				var ids []string
+				require.NoError(t, result.Unmarshal(&ids))
+				assert.Equal(t, []string{"a", "b"}, ids)
+			})
+		})
+	}
+
+	t.Run("not sent by default", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				_, ok := r.URL.Query()["returnQuery"]
+				assert.False(t, ok)
+
+				w.WriteHeader(http.StatusOK)
+				_, err := w.Write(mustJSONBytes(&api.QueryResponse{Query: "*"}))
+				assert.NoError(t, err)
+			})
+
+			result, err := s.client.Query("*").ReturnQuery(true).Do(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, "*", result.Query)
+		})
+	})
+}
+
+func TestQuery_boolParams(t *testing.T) {
+	yes, no := true, false
+
+	for _, tc := range []struct {
+		desc   string
+		val    interface{}
+		expect string
+	}{
+		{"true", true, "true"},
+		{"false", false, "false"},
+		{"pointer to true", &yes, "true"},
+		{"pointer to false", &no, "false"},
+		{"nil pointer", (*bool)(nil), "null"},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			t.Run("GET", func(t *testing.T) {
+				withSuite(t, func(s *Suite) {
+					s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+						assert.Equal(t, []string{tc.expect}, r.URL.Query()["$val"])
+
+						w.WriteHeader(http.StatusOK)
+						_, err := w.Write(mustJSONBytes(&api.QueryResponse{}))
+						assert.NoError(t, err)
+					})
+
+					_, err := s.client.Query("*[published == $val]").Param("val", tc.val).Do(context.Background())
+					require.NoError(t, err)
+				})
+			})
+
+			t.Run("POST", func(t *testing.T) {
+				groq := "*[foo=='" + strings.Repeat("foo", 1000) + "' && published == $val]"
+
+				withSuite(t, func(s *Suite) {
+					s.mux.Post("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+						var req struct {
+							Params map[string]json.RawMessage `json:"params"`
+						}
+						require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+						assert.Contains(t, req.Params, "val")
+						assert.Equal(t, tc.expect, string(req.Params["val"]))
+
+						w.WriteHeader(http.StatusOK)
+						_, err := w.Write(mustJSONBytes(&api.QueryResponse{}))
+						assert.NoError(t, err)
+					})
+
+					_, err := s.client.Query(groq).Param("val", tc.val).Do(context.Background())
+					require.NoError(t, err)
+				})
+			})
+		})
+	}
+}
+
+func TestQuery_typedParams(t *testing.T) {
+	for _, tc := range []struct {
+		desc   string
+		build  func(qb *sanity.QueryBuilder)
+		expect string
+	}{
+		{"string", func(qb *sanity.QueryBuilder) { qb.ParamString("val", `say "hi"`) }, `"say \"hi\""`},
+		{"int", func(qb *sanity.QueryBuilder) { qb.ParamInt("val", 9007199254740993) }, `9007199254740993`},
+		{"whole float", func(qb *sanity.QueryBuilder) { qb.ParamFloat("val", 1) }, `1.0`},
+		{"fractional float", func(qb *sanity.QueryBuilder) { qb.ParamFloat("val", 1.25) }, `1.25`},
+		{"large float", func(qb *sanity.QueryBuilder) { qb.ParamFloat("val", 1e21) }, `1e+21`},
+		{
+			"time",
+			func(qb *sanity.QueryBuilder) {
+				qb.ParamTime("val", time.Date(2021, 3, 25, 13, 0, 0, 500000000, time.FixedZone("CET", 3600)))
+			},
+			`"2021-03-25T12:00:00.5Z"`,
+		},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			withSuite(t, func(s *Suite) {
+				s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+					assert.Equal(t, tc.expect, r.URL.Query().Get("$val"))
+
+					w.WriteHeader(http.StatusOK)
+					_, err := w.Write(mustJSONBytes(&api.QueryResponse{}))
+					assert.NoError(t, err)
+				})
+
+				qb := s.client.Query("*[value == $val]")
+				tc.build(qb)
+				_, err := qb.Do(context.Background())
+				require.NoError(t, err)
+			})
+		})
+	}
+
+	t.Run("string with control and non-printable characters", func(t *testing.T) {
+		val := "a\x7fb\a\v\x00\U0001F600\u2028"
+		for _, tc := range []struct {
+			desc string
+			groq string
+		}{
+			{"GET", "*[value == $val]"},
+			{"POST", "*[foo=='" + strings.Repeat("foo", 1000) + "' && value == $val]"},
+		} {
+			tc := tc
+			t.Run(tc.desc, func(t *testing.T) {
+				withSuite(t, func(s *Suite) {
+					check := func(raw string) {
+						var got string
+						require.NoError(t, json.Unmarshal([]byte(raw), &got), raw)
+						assert.Equal(t, val, got)
+					}
+					s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+						check(r.URL.Query().Get("$val"))
+						_, err := w.Write(mustJSONBytes(&api.QueryResponse{}))
+						assert.NoError(t, err)
+					})
+					s.mux.Post("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+						var req api.QueryRequest
+						require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+						check(string(*req.Params["val"]))
+						_, err := w.Write(mustJSONBytes(&api.QueryResponse{}))
+						assert.NoError(t, err)
+					})
+
+					_, err := s.client.Query(tc.groq).ParamString("val", val).Do(context.Background())
+					require.NoError(t, err)
+				})
+			})
+		}
+	})
+
+	t.Run("NaN float", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			_, err := s.client.Query("*[value == $val]").ParamFloat("val", math.NaN()).Do(context.Background())
+			require.Error(t, err)
+		})
+	})
+}
+
+func TestQuery_Locale(t *testing.T) {
+	t.Run("sets lang param", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, `"nb-NO"`, r.URL.Query().Get("$lang"))
+				assert.Equal(t, `"post"`, r.URL.Query().Get("$type"))
+
+				w.WriteHeader(http.StatusOK)
+				_, err := w.Write(mustJSONBytes(&api.QueryResponse{}))
+				assert.NoError(t, err)
+			})
+
+			_, err := s.client.Query("*[_type == $type && language == $lang]").
+				Param("type", "post").
+				Locale("nb-NO").
+				Do(context.Background())
+			require.NoError(t, err)
+		})
+	})
+
+	t.Run("rejects colliding params", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			_, err := s.client.Query("*").Param("lang", "en").Locale("nb").Do(context.Background())
+			require.Error(t, err)
+
+			_, err = s.client.Query("*").Locale("nb").Param("lang", "en").Do(context.Background())
+			require.Error(t, err)
+		})
+	})
+}
+
+func TestQuery_ParamsJSON(t *testing.T) {
+	raw := json.RawMessage(`{"type":"post","limit":10}`)
+
+	t.Run("GET", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, `"post"`, r.URL.Query().Get("$type"))
+				assert.Equal(t, "10", r.URL.Query().Get("$limit"))
+				assert.Equal(t, `"x"`, r.URL.Query().Get("$extra"))
+
+				w.WriteHeader(http.StatusOK)
+				_, err := w.Write(mustJSONBytes(&api.QueryResponse{}))
+				assert.NoError(t, err)
+			})
+
+			_, err := s.client.Query("*[_type == $type][0...$limit]").
+				ParamsJSON(raw).
+				Param("extra", "x").
+				Do(context.Background())
+			require.NoError(t, err)
+		})
+	})
+
+	t.Run("POST", func(t *testing.T) {
+		groq := "*[foo=='" + strings.Repeat("foo", 1000) + "' && _type == $type][0...$limit]"
+
+		withSuite(t, func(s *Suite) {
+			s.mux.Post("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				var req struct {
+					Params json.RawMessage `json:"params"`
+				}
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+				assert.Equal(t, string(raw), string(req.Params))
+
+				w.WriteHeader(http.StatusOK)
+				_, err := w.Write(mustJSONBytes(&api.QueryResponse{}))
+				assert.NoError(t, err)
+			})
+
+			_, err := s.client.Query(groq).ParamsJSON(raw).Do(context.Background())
+			require.NoError(t, err)
+		})
+	})
+
+	t.Run("GET with null param", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "null", r.URL.Query().Get("$a"))
+				assert.Equal(t, `"x"`, r.URL.Query().Get("$b"))
+
+				w.WriteHeader(http.StatusOK)
+				_, err := w.Write(mustJSONBytes(&api.QueryResponse{}))
+				assert.NoError(t, err)
+			})
+
+			_, err := s.client.Query("*[a == $a]").
+				ParamsJSON(json.RawMessage(`{"a":null}`)).
+				Param("b", "x").
+				Do(context.Background())
+			require.NoError(t, err)
+		})
+	})
+
+	t.Run("rejects non-object", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			for _, raw := range []string{`[1]`, `null`, `"x"`, `{`} {
+				_, err := s.client.Query("*").ParamsJSON(json.RawMessage(raw)).Do(context.Background())
+				require.Error(t, err, raw)
+			}
+		})
+	})
+}
+
+func TestQuery_resultTransformer(t *testing.T) {
+	serve := func(s *Suite) {
+		s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+			_, err := w.Write(mustJSONBytes(&api.QueryResponse{Result: mustJSONMsg("a")}))
+			assert.NoError(t, err)
+		})
+	}
+	appendString := func(suffix string) func(*json.RawMessage) (*json.RawMessage, error) {
+		return func(result *json.RawMessage) (*json.RawMessage, error) {
+			var s string
+			if err := json.Unmarshal(*result, &s); err != nil {
+				return nil, err
+			}
+			return mustJSONMsg(s + suffix), nil
+		}
+	}
+
+	t.Run("runs in order", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			serve(s)
+
+			result, err := s.client.Query("*").Do(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, `"abc"`, string(*result.Result))
+			assert.Equal(t, `"a"`, string(*result.RawResponse().Result))
+		},
+			sanity.WithResultTransformer(appendString("b")),
+			sanity.WithResultTransformer(appendString("c")),
+		)
+	})
+
+	t.Run("propagates errors", func(t *testing.T) {
+		errTransform := errors.New("transform failed")
+		calls := 0
+
+		withSuite(t, func(s *Suite) {
+			serve(s)
+
+			_, err := s.client.Query("*").Do(context.Background())
+			require.Error(t, err)
+			assert.True(t, errors.Is(err, errTransform))
+			assert.Equal(t, 0, calls)
+		},
+			sanity.WithResultTransformer(func(*json.RawMessage) (*json.RawMessage, error) {
+				return nil, errTransform
+			}),
+			sanity.WithResultTransformer(func(result *json.RawMessage) (*json.RawMessage, error) {
+				calls++
+				return result, nil
+			}),
+		)
+	})
+}
+
+func TestQueryResult_Unmarshal(t *testing.T) {
+	result := &sanity.QueryResult{Result: mustJSONMsg(map[string]string{"title": "Hello"}), Query: `*[_id == "post1"][0]`}
+
+	t.Run("nil dest", func(t *testing.T) {
+		err := result.Unmarshal(nil)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "dest must be a non-nil pointer")
+		assert.Contains(t, err.Error(), `*[_id == \"post1\"][0]`)
+	})
+
+	t.Run("nil pointer dest", func(t *testing.T) {
+		var dest *map[string]string
+		err := result.Unmarshal(dest)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "dest must be a non-nil pointer")
+	})
+
+	t.Run("non-pointer dest", func(t *testing.T) {
+		err := (&sanity.QueryResult{Result: mustJSONMsg("x")}).Unmarshal(map[string]string{})
+		require.Error(t, err)
+		assert.Equal(t, "unmarshaling query result: dest must be a non-nil pointer, got map[string]string", err.Error())
+	})
+
+	t.Run("pointer dest", func(t *testing.T) {
+		var dest struct {
+			Title string `json:"title"`
+		}
+		require.NoError(t, result.Unmarshal(&dest))
+		assert.Equal(t, "Hello", dest.Title)
+	})
+
+	t.Run("zeroes dest without result", func(t *testing.T) {
+		dest := map[string]string{"title": "stale"}
+		require.NoError(t, (&sanity.QueryResult{}).Unmarshal(&dest))
+		assert.Nil(t, dest)
+	})
+}
+
+func TestQuery_jsonNumbers(t *testing.T) {
+	withSuite(t, func(s *Suite) {
+		s.mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+			_, err := w.Write([]byte(`{"result":{"big":9007199254740993,"small":1.5}}`))
+			assert.NoError(t, err)
+		})
+
+		result, err := s.client.Query("*").Do(context.Background())
+		require.NoError(t, err)
+
+		var untyped interface{}
+		require.NoError(t, result.Unmarshal(&untyped))
+		assert.Equal(t, map[string]interface{}{
+			"big":   json.Number("9007199254740993"),
+			"small": json.Number("1.5"),
+		}, untyped)
+
+		var typed struct {
+			Big   int64   `json:"big"`
+			Small float64 `json:"small"`
+		}
+		require.NoError(t, result.Unmarshal(&typed))
+		assert.Equal(t, int64(9007199254740993), typed.Big)
+		assert.Equal(t, 1.5, typed.Small)
+	}, sanity.WithJSONNumbers(true))
+}
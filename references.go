@@ -0,0 +1,171 @@
+package sanity
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/sanity-io/client-go/api"
+)
+
+// ResolveReferences walks a JSON value, such as a query result, and replaces references
+// ({"_ref": "id"}) with the documents they point to, fetched with GetDocuments. Documents
+// are fetched in one batch per level of nesting, each at most once, and references within
+// inlined documents are resolved in turn, up to maxDepth levels. A reference to a document
+// that is already being inlined further up the same branch is left as is, so reference
+// cycles terminate, as are references to documents that do not exist. The _key of an
+// inlined reference is preserved.
+//
+// The documents are fetched with the tag of ctx, if set with ContextWithTag.
+//
+// Where possible, prefer dereferencing in the query itself with GROQ's -> operator.
+func (c *Client) ResolveReferences(ctx context.Context, raw json.RawMessage, maxDepth int) (json.RawMessage, error) {
+	var root interface{}
+	if err := unmarshalJSON(raw, &root, c.useNumber); err != nil {
+		return nil, fmt.Errorf("decoding value: %w", err)
+	}
+
+	holder := []interface{}{root}
+	sites := collectReferenceSites(holder, nil)
+	docs := map[string]api.Document{}
+
+	for depth := 0; depth < maxDepth && len(sites) > 0; depth++ {
+		if err := c.fetchMissingDocuments(ctx, sites, docs); err != nil {
+			return nil, err
+		}
+
+		var next []referenceSite
+		for _, site := range sites {
+			doc := docs[site.ref]
+			if doc == nil || site.isCycle() {
+				continue
+			}
+
+			inlined := deepCopyJSON(map[string]interface{}(doc)).(map[string]interface{})
+			if key, ok := site.node["_key"]; ok {
+				inlined["_key"] = key
+			}
+			site.set(inlined)
+
+			ancestors := make([]string, len(site.ancestors), len(site.ancestors)+1)
+			copy(ancestors, site.ancestors)
+			next = append(next, collectReferenceSites(inlined, append(ancestors, site.ref))...)
+		}
+		sites = next
+	}
+
+	b, err := json.Marshal(holder[0])
+	if err != nil {
+		return nil, fmt.Errorf("encoding value: %w", err)
+	}
+	return b, nil
+}
+
+// referenceSite is a reference found while walking a value, along with a way to replace it.
+type referenceSite struct {
+	ref       string
+	node      map[string]interface{}
+	set       func(interface{})
+	ancestors []string
+}
+
+func (s referenceSite) isCycle() bool {
+	for _, id := range s.ancestors {
+		if id == s.ref {
+			return true
+		}
+	}
+	return false
+}
+
+func collectReferenceSites(node interface{}, ancestors []string) []referenceSite {
+	var sites []referenceSite
+
+	visit := func(child interface{}, set func(interface{})) {
+		if m, ok := child.(map[string]interface{}); ok {
+			if ref, ok := m["_ref"].(string); ok && ref != "" {
+				sites = append(sites, referenceSite{
+					ref:       ref,
+					node:      m,
+					set:       set,
+					ancestors: ancestors,
+				})
+				return
+			}
+		}
+		sites = append(sites, collectReferenceSites(child, ancestors)...)
+	}
+
+	switch node := node.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(node))
+		for k := range node {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			k := k
+			visit(node[k], func(v interface{}) { node[k] = v })
+		}
+	case []interface{}:
+		for i := range node {
+			i := i
+			visit(node[i], func(v interface{}) { node[i] = v })
+		}
+	}
+	return sites
+}
+
+func (c *Client) fetchMissingDocuments(ctx context.Context, sites []referenceSite, docs map[string]api.Document) error {
+	seen := map[string]bool{}
+	var ids []string
+	for _, site := range sites {
+		if _, ok := docs[site.ref]; !ok && !seen[site.ref] {
+			seen[site.ref] = true
+			ids = append(ids, site.ref)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	resp, err := c.GetDocuments(ids...).Do(ctx)
+	if err != nil {
+		return fmt.Errorf("fetching referenced documents: %w", err)
+	}
+
+	for _, doc := range resp.Documents {
+		if id, ok := doc["_id"].(string); ok {
+			docs[id] = doc
+		}
+	}
+
+	// Record the documents that do not exist, so that they are not requested again.
+	for _, id := range ids {
+		if _, ok := docs[id]; !ok {
+			docs[id] = nil
+		}
+	}
+	return nil
+}
+
+func deepCopyJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = deepCopyJSON(e)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, e := range v {
+			s[i] = deepCopyJSON(e)
+		}
+		return s
+	default:
+		return v
+	}
+}
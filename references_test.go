@@ -0,0 +1,173 @@
+package sanity_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sanity "github.com/sanity-io/client-go"
+	"github.com/sanity-io/client-go/api"
+)
+
+func serveDocuments(t *testing.T, s *Suite, docs map[string]string, requested *[][]string) {
+	s.mux.Get("/v1/data/doc/myDataset/{ids}", func(w http.ResponseWriter, r *http.Request) {
+		ids := strings.Split(chi.URLParam(r, "ids"), ",")
+		*requested = append(*requested, ids)
+
+		var resp api.GetDocumentsResponse
+		for _, id := range ids {
+			if doc, ok := docs[id]; ok {
+				var d api.Document
+				require.NoError(t, json.Unmarshal([]byte(doc), &d))
+				resp.Documents = append(resp.Documents, d)
+			}
+		}
+
+		w.WriteHeader(http.StatusOK)
+		_, err := w.Write(mustJSONBytes(&resp))
+		assert.NoError(t, err)
+	})
+}
+
+func TestResolveReferences_jsonNumbers(t *testing.T) {
+	for _, tc := range []struct {
+		desc   string
+		enable bool
+		want   string
+	}{
+		{desc: "enabled", enable: true, want: "9007199254740993"},
+		{desc: "disabled", enable: false, want: "9007199254740992"},
+	} {
+		tc := tc
+		t.Run(tc.desc, func(t *testing.T) {
+			withSuite(t, func(s *Suite) {
+				s.mux.Get("/v1/data/doc/myDataset/doc1", func(w http.ResponseWriter, r *http.Request) {
+					_, err := w.Write([]byte(`{"documents":[{"_id":"doc1","count":9007199254740993}]}`))
+					assert.NoError(t, err)
+				})
+
+				result, err := s.client.ResolveReferences(context.Background(), json.RawMessage(
+					`{"count": 9007199254740993, "doc": {"_ref": "doc1"}}`), 1)
+				require.NoError(t, err)
+				assert.Equal(t,
+					`{"count":`+tc.want+`,"doc":{"_id":"doc1","count":`+tc.want+`}}`,
+					string(result))
+			}, sanity.WithJSONNumbers(tc.enable))
+		})
+	}
+}
+
+func TestResolveReferences(t *testing.T) {
+	docs := map[string]string{
+		"author1":  `{"_id": "author1", "name": "Jane", "company": {"_ref": "company1", "_type": "reference"}}`,
+		"author2":  `{"_id": "author2", "name": "John"}`,
+		"company1": `{"_id": "company1", "name": "Acme"}`,
+		"author3":  `{"_id": "author3", "name": "Jim", "company": {"_ref": "missing"}}`,
+		"a":        `{"_id": "a", "next": {"_ref": "b"}}`,
+		"b":        `{"_id": "b", "next": {"_ref": "a"}}`,
+	}
+
+	t.Run("nested references", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			var requested [][]string
+			serveDocuments(t, s, docs, &requested)
+
+			result, err := s.client.ResolveReferences(context.Background(), json.RawMessage(`[
+				{"_id": "post1", "authors": [
+					{"_key": "k1", "_ref": "author1", "_type": "reference"},
+					{"_key": "k2", "_ref": "author2", "_type": "reference"},
+					{"_key": "k3", "_ref": "missing", "_type": "reference"}
+				]}
+			]`), 3)
+			require.NoError(t, err)
+
+			assert.JSONEq(t, `[
+				{"_id": "post1", "authors": [
+					{"_key": "k1", "_id": "author1", "name": "Jane", "company": {"_id": "company1", "name": "Acme"}},
+					{"_key": "k2", "_id": "author2", "name": "John"},
+					{"_key": "k3", "_ref": "missing", "_type": "reference"}
+				]}
+			]`, string(result))
+
+			assert.Equal(t, [][]string{{"author1", "author2", "missing"}, {"company1"}}, requested)
+		})
+	})
+
+	t.Run("requests dangling references once", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			var requested [][]string
+			serveDocuments(t, s, docs, &requested)
+
+			result, err := s.client.ResolveReferences(context.Background(), json.RawMessage(
+				`[{"_ref": "missing"}, {"_ref": "author3"}]`), 3)
+			require.NoError(t, err)
+
+			assert.JSONEq(t, `[
+				{"_ref": "missing"},
+				{"_id": "author3", "name": "Jim", "company": {"_ref": "missing"}}
+			]`, string(result))
+			assert.Equal(t, [][]string{{"missing", "author3"}}, requested)
+		})
+	})
+
+	t.Run("limited depth", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			var requested [][]string
+			serveDocuments(t, s, docs, &requested)
+
+			result, err := s.client.ResolveReferences(context.Background(), json.RawMessage(
+				`{"author": {"_ref": "author1"}}`), 1)
+			require.NoError(t, err)
+
+			assert.JSONEq(t, `{"author": {
+				"_id": "author1", "name": "Jane", "company": {"_ref": "company1", "_type": "reference"}
+			}}`, string(result))
+			assert.Len(t, requested, 1)
+		})
+	})
+
+	t.Run("reference cycle", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			var requested [][]string
+			serveDocuments(t, s, docs, &requested)
+
+			result, err := s.client.ResolveReferences(context.Background(), json.RawMessage(
+				`{"start": {"_ref": "a"}}`), 10)
+			require.NoError(t, err)
+
+			assert.JSONEq(t, `{"start": {
+				"_id": "a", "next": {"_id": "b", "next": {"_ref": "a"}}
+			}}`, string(result))
+			assert.Equal(t, [][]string{{"a"}, {"b"}}, requested)
+		})
+	})
+
+	t.Run("tags sub-requests with context tag", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			var tags []string
+			s.mux.Get("/v1/data/doc/myDataset/{ids}", func(w http.ResponseWriter, r *http.Request) {
+				tags = append(tags, r.URL.Query().Get("tag"))
+
+				var resp api.GetDocumentsResponse
+				for _, id := range strings.Split(chi.URLParam(r, "ids"), ",") {
+					var d api.Document
+					require.NoError(t, json.Unmarshal([]byte(docs[id]), &d))
+					resp.Documents = append(resp.Documents, d)
+				}
+				_, err := w.Write(mustJSONBytes(&resp))
+				assert.NoError(t, err)
+			})
+
+			ctx := sanity.ContextWithTag(context.Background(), "resolve")
+			_, err := s.client.ResolveReferences(ctx, json.RawMessage(`{"author": {"_ref": "author1"}}`), 2)
+			require.NoError(t, err)
+			assert.Equal(t, []string{"resolve", "resolve"}, tags)
+		}, sanity.WithTag("default"))
+	})
+}
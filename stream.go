@@ -0,0 +1,107 @@
+package sanity
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// arrayStream decodes the elements of an array in a top-level field of a JSON object one
+// at a time, so that the whole response never has to be held in memory. Other fields of
+// the object are skipped.
+type arrayStream struct {
+	dec     *json.Decoder
+	field   string
+	started bool
+	done    bool
+}
+
+func newArrayStream(r io.Reader, field string) *arrayStream {
+	return &arrayStream{dec: json.NewDecoder(r), field: field}
+}
+
+// next returns the next element of the array, or io.EOF when there are no more elements.
+func (s *arrayStream) next() (json.RawMessage, error) {
+	if s.done {
+		return nil, io.EOF
+	}
+
+	if !s.started {
+		s.started = true
+		if err := s.seek(); err != nil {
+			s.done = true
+			return nil, err
+		}
+		if s.done {
+			return nil, io.EOF
+		}
+	}
+
+	if !s.dec.More() {
+		s.done = true
+		if _, err := s.dec.Token(); err != nil {
+			return nil, fmt.Errorf("decoding %q: %w", s.field, err)
+		}
+		return nil, io.EOF
+	}
+
+	var elem json.RawMessage
+	if err := s.dec.Decode(&elem); err != nil {
+		s.done = true
+		return nil, fmt.Errorf("decoding %q element: %w", s.field, err)
+	}
+	return elem, nil
+}
+
+// seek advances the decoder to the first element of the array. If the field is missing or
+// null, the stream is marked as done.
+func (s *arrayStream) seek() error {
+	if err := s.expectDelim('{'); err != nil {
+		return err
+	}
+
+	for s.dec.More() {
+		tok, err := s.dec.Token()
+		if err != nil {
+			return fmt.Errorf("decoding response: %w", err)
+		}
+
+		if key, ok := tok.(string); ok && key == s.field {
+			tok, err := s.dec.Token()
+			if err != nil {
+				return fmt.Errorf("decoding %q: %w", s.field, err)
+			}
+			if tok == nil {
+				s.done = true
+				return nil
+			}
+			if delim, ok := tok.(json.Delim); !ok || delim != '[' {
+				return fmt.Errorf("expected %q to be an array", s.field)
+			}
+			return nil
+		}
+
+		var skip json.RawMessage
+		if err := s.dec.Decode(&skip); err != nil {
+			return fmt.Errorf("decoding response: %w", err)
+		}
+	}
+
+	s.done = true
+	return nil
+}
+
+func (s *arrayStream) expectDelim(want json.Delim) error {
+	tok, err := s.dec.Token()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return fmt.Errorf("decoding response: %w", err)
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != want {
+		return fmt.Errorf("decoding response: expected %q, got %v", want, tok)
+	}
+	return nil
+}
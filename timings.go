@@ -0,0 +1,135 @@
+package sanity
+
+import (
+	"context"
+	"crypto/tls"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// RequestTimings is a breakdown of the time taken by a request attempt, as reported to the
+// function set with WithHTTPTrace. Phases that did not take place, such as DNS lookup for a
+// reused connection or TLS for plain HTTP, are zero.
+type RequestTimings struct {
+	Method string
+	URL    string
+
+	// Attempt is the number of the attempt, starting at 1 for the first, as in the attempt
+	// field of log entries. The retry numbered n in Callbacks.OnRetry is attempt n+1.
+	Attempt int
+
+	// ReusedConn is true if the request was sent on a connection already open.
+	ReusedConn bool
+
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+
+	// TimeToFirstByte is the time from the start of the request until the first byte of the
+	// response was received.
+	TimeToFirstByte time.Duration
+
+	// BodyRead is the time from the first byte of the response until its body was closed.
+	BodyRead time.Duration
+
+	// Total is the time from the start of the request until its body was closed, or until it
+	// failed.
+	Total time.Duration
+
+	// Err is the error of an attempt that got no response.
+	Err error
+}
+
+// requestTrace collects the timings of a request attempt through httptrace hooks, which may
+// be called from other goroutines.
+type requestTrace struct {
+	mu                  sync.Mutex
+	start               time.Time
+	dnsStart, dnsDone   time.Time
+	connStart, connDone time.Time
+	tlsStart, tlsDone   time.Time
+	firstByte           time.Time
+	timings             RequestTimings
+	report              func(*RequestTimings)
+	reported            bool
+}
+
+func newRequestTrace(req *http.Request, attempt int, report func(*RequestTimings)) *requestTrace {
+	return &requestTrace{
+		start:   time.Now(),
+		timings: RequestTimings{Method: req.Method, URL: req.URL.String(), Attempt: attempt},
+		report:  report,
+	}
+}
+
+// withContext returns the context with the trace hooks added to any already present.
+func (t *requestTrace) withContext(ctx context.Context) context.Context {
+	now := func(ts *time.Time) {
+		t.mu.Lock()
+		*ts = time.Now()
+		t.mu.Unlock()
+	}
+
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		DNSStart:     func(httptrace.DNSStartInfo) { now(&t.dnsStart) },
+		DNSDone:      func(httptrace.DNSDoneInfo) { now(&t.dnsDone) },
+		ConnectStart: func(string, string) { now(&t.connStart) },
+		ConnectDone:  func(string, string, error) { now(&t.connDone) },
+		TLSHandshakeStart: func() {
+			now(&t.tlsStart)
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			now(&t.tlsDone)
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.mu.Lock()
+			t.timings.ReusedConn = info.Reused
+			t.mu.Unlock()
+		},
+		GotFirstResponseByte: func() { now(&t.firstByte) },
+	})
+}
+
+// finish reports the timings, once.
+func (t *requestTrace) finish(err error) {
+	end := time.Now()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.reported {
+		return
+	}
+	t.reported = true
+
+	span := func(from, to time.Time) time.Duration {
+		if from.IsZero() || to.IsZero() {
+			return 0
+		}
+		return to.Sub(from)
+	}
+
+	timings := t.timings
+	timings.DNS = span(t.dnsStart, t.dnsDone)
+	timings.Connect = span(t.connStart, t.connDone)
+	timings.TLS = span(t.tlsStart, t.tlsDone)
+	timings.TimeToFirstByte = span(t.start, t.firstByte)
+	timings.BodyRead = span(t.firstByte, end)
+	timings.Total = end.Sub(t.start)
+	timings.Err = err
+	t.report(&timings)
+}
+
+// traceBody reports the timings of the trace when the body is closed.
+type traceBody struct {
+	io.ReadCloser
+	trace *requestTrace
+}
+
+func (b *traceBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.trace.finish(nil)
+	return err
+}
@@ -0,0 +1,94 @@
+package sanity
+
+import (
+	"context"
+	"errors"
+)
+
+// NewTransaction returns a new transaction. A transaction collects mutations that may be
+// added from several places, such as different functions, and commits them in a single
+// request. Sanity applies a transaction atomically: either all of its mutations are applied,
+// or, if any of them fails, none are. There is therefore no rollback to perform on failure.
+//
+// A transaction is not safe for concurrent use.
+func (c *Client) NewTransaction() *Transaction {
+	return &Transaction{mb: c.Mutate()}
+}
+
+// Transaction is a set of mutations that are committed together.
+type Transaction struct {
+	mb        *MutationBuilder
+	id        string
+	committed bool
+}
+
+// ID sets the transaction ID. If not set, an ID is generated on commit.
+func (t *Transaction) ID(id string) *Transaction {
+	t.id = id
+	return t
+}
+
+// Tag sets the request tag, overriding the client default set with WithTag.
+func (t *Transaction) Tag(tag string) *Transaction {
+	t.mb.Tag(tag)
+	return t
+}
+
+func (t *Transaction) Create(doc interface{}) *Transaction {
+	t.mb.Create(doc)
+	return t
+}
+
+func (t *Transaction) CreateIfNotExists(doc interface{}) *Transaction {
+	t.mb.CreateIfNotExists(doc)
+	return t
+}
+
+func (t *Transaction) CreateOrReplace(doc interface{}) *Transaction {
+	t.mb.CreateOrReplace(doc)
+	return t
+}
+
+func (t *Transaction) Delete(id string) *Transaction {
+	t.mb.Delete(id)
+	return t
+}
+
+// Patch adds a patch of the document with the given ID to the transaction, and calls fn with
+// its builder to set the operations of the patch.
+func (t *Transaction) Patch(id string, fn func(*PatchBuilder)) *Transaction {
+	fn(t.mb.Patch(id))
+	return t
+}
+
+// Mutations returns the underlying mutation builder, which can be used to configure the
+// request, such as with Visibility or DryRun.
+func (t *Transaction) Mutations() *MutationBuilder {
+	return t.mb
+}
+
+// Commit sends all mutations in a single request. A transaction can only be committed once
+// successfully; if the request fails, it can be committed again, with the same transaction
+// ID, so that a retry is not applied twice if the first attempt was in fact applied. A dry
+// run does not commit the transaction, so it can still be committed for real afterwards.
+// On API request failure, this will return an error of type *RequestError.
+func (t *Transaction) Commit(ctx context.Context) (*MutateResult, error) {
+	if t.committed {
+		return nil, errors.New("transaction has already been committed")
+	}
+
+	if t.id == "" {
+		id, err := generateID()
+		if err != nil {
+			return nil, err
+		}
+		t.id = id
+	}
+
+	result, err := t.mb.TransactionID(t.id).Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+	t.committed = !t.mb.dryRun
+	return result, nil
+}
@@ -0,0 +1,166 @@
+package sanity_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sanity "github.com/sanity-io/client-go"
+	"github.com/sanity-io/client-go/api"
+)
+
+func TestTransaction(t *testing.T) {
+	addPost := func(tx *sanity.Transaction) {
+		tx.Create(map[string]string{"_id": "post1", "_type": "post"})
+	}
+	bumpCounter := func(tx *sanity.Transaction) *sanity.Transaction {
+		return tx.Patch("counter", func(pb *sanity.PatchBuilder) {
+			pb.Inc("posts", 1)
+		})
+	}
+
+	t.Run("commits all mutations in one request", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			calls := 0
+			s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				assert.Equal(t, "tx1", r.URL.Query().Get("transactionId"))
+
+				var req api.MutateRequest
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+				assert.Equal(t, []*api.MutationItem{
+					{Create: mustJSONMsg(map[string]string{"_id": "post1", "_type": "post"})},
+					{Patch: &api.Patch{ID: "counter", Inc: map[string]float64{"posts": 1}}},
+					{Delete: &api.Delete{ID: "old"}},
+				}, req.Mutations)
+
+				w.WriteHeader(http.StatusOK)
+				_, err := w.Write(mustJSONBytes(&api.MutateResponse{TransactionID: "tx1"}))
+				assert.NoError(t, err)
+			})
+
+			tx := s.client.NewTransaction().ID("tx1")
+			addPost(tx)
+			bumpCounter(tx).Delete("old")
+
+			result, err := tx.Commit(context.Background())
+			require.NoError(t, err)
+			assert.Equal(t, "tx1", result.TransactionID)
+			assert.Equal(t, 1, calls)
+
+			_, err = tx.Commit(context.Background())
+			require.Error(t, err)
+			assert.Equal(t, 1, calls)
+		})
+	})
+
+	t.Run("can be committed again after failure", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			var txIDs []string
+			s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				txIDs = append(txIDs, r.URL.Query().Get("transactionId"))
+				if len(txIDs) == 1 {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+
+				w.WriteHeader(http.StatusOK)
+				_, err := w.Write(mustJSONBytes(&api.MutateResponse{TransactionID: txIDs[0]}))
+				assert.NoError(t, err)
+			})
+
+			tx := s.client.NewTransaction()
+			addPost(tx)
+
+			_, err := tx.Commit(context.Background())
+			require.Error(t, err)
+
+			_, err = tx.Commit(context.Background())
+			require.NoError(t, err)
+
+			require.Len(t, txIDs, 2)
+			assert.Equal(t, txIDs[0], txIDs[1])
+		})
+	})
+
+	t.Run("dry run does not commit", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			var dryRuns []string
+			s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				dryRuns = append(dryRuns, r.URL.Query().Get("dryRun"))
+
+				w.WriteHeader(http.StatusOK)
+				_, err := w.Write(mustJSONBytes(&api.MutateResponse{}))
+				assert.NoError(t, err)
+			})
+
+			tx := s.client.NewTransaction()
+			addPost(tx)
+
+			tx.Mutations().DryRun(true)
+			_, err := tx.Commit(context.Background())
+			require.NoError(t, err)
+
+			tx.Mutations().DryRun(false)
+			_, err = tx.Commit(context.Background())
+			require.NoError(t, err)
+
+			_, err = tx.Commit(context.Background())
+			require.Error(t, err)
+			assert.Equal(t, []string{"true", "false"}, dryRuns)
+		})
+	})
+
+	t.Run("generates transaction ID", func(t *testing.T) {
+		withSuite(t, func(s *Suite) {
+			s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+				assert.NotEmpty(t, r.URL.Query().Get("transactionId"))
+
+				w.WriteHeader(http.StatusOK)
+				_, err := w.Write(mustJSONBytes(&api.MutateResponse{}))
+				assert.NoError(t, err)
+			})
+
+			tx := s.client.NewTransaction()
+			addPost(tx)
+			_, err := tx.Commit(context.Background())
+			require.NoError(t, err)
+		})
+	})
+
+	t.Run("tag", func(t *testing.T) {
+		for _, tc := range []struct {
+			desc string
+			tag  string
+			want string
+		}{
+			{desc: "honors default", want: "default"},
+			{desc: "can be overridden", tag: "custom", want: "custom"},
+		} {
+			tc := tc
+			t.Run(tc.desc, func(t *testing.T) {
+				withSuite(t, func(s *Suite) {
+					s.mux.Post("/v1/data/mutate/myDataset", func(w http.ResponseWriter, r *http.Request) {
+						assert.Equal(t, tc.want, r.URL.Query().Get("tag"))
+
+						w.WriteHeader(http.StatusOK)
+						_, err := w.Write(mustJSONBytes(&api.MutateResponse{}))
+						assert.NoError(t, err)
+					})
+
+					tx := s.client.NewTransaction()
+					if tc.tag != "" {
+						tx.Tag(tc.tag)
+					}
+					addPost(tx)
+					_, err := tx.Commit(context.Background())
+					require.NoError(t, err)
+				}, sanity.WithTag("default"))
+			})
+		}
+	})
+}
@@ -0,0 +1,103 @@
+package sanity
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+const (
+	// ProtocolHTTP1 restricts requests to HTTP/1.1.
+	ProtocolHTTP1 = "HTTP/1.1"
+
+	// ProtocolHTTP2 makes requests use HTTP/2 when the server supports it over HTTPS.
+	ProtocolHTTP2 = "HTTP/2"
+)
+
+// protocolTransport sends requests to the CDN host with one transport and all other
+// requests with another, so that each can be restricted to a different HTTP protocol.
+type protocolTransport struct {
+	// base is the transport of the HTTP client before it was wrapped.
+	base    http.RoundTripper
+	api     http.RoundTripper
+	cdn     http.RoundTripper
+	cdnHost string
+}
+
+func (t *protocolTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.cdnHost != "" && req.URL.Host == t.cdnHost {
+		return t.cdn.RoundTrip(req)
+	}
+	return t.api.RoundTrip(req)
+}
+
+// initProtocols wraps the transport of the HTTP client to apply the protocols set with
+// WithProtocols. It must be called after the base URLs are set.
+func (c *Client) initProtocols() error {
+	for _, proto := range []string{c.apiProto, c.cdnProto} {
+		switch proto {
+		case "", ProtocolHTTP1, ProtocolHTTP2:
+		default:
+			return fmt.Errorf("invalid protocol %q, must be %q or %q", proto, ProtocolHTTP1, ProtocolHTTP2)
+		}
+	}
+
+	// A cloned client may already have a wrapped transport.
+	base := c.hc.Transport
+	if pt, ok := base.(*protocolTransport); ok {
+		base = pt.base
+	}
+
+	if c.apiProto == "" && c.cdnProto == "" {
+		if base != c.hc.Transport {
+			hc := *c.hc
+			hc.Transport = base
+			c.hc = &hc
+		}
+		return nil
+	}
+
+	rt := base
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	transport, ok := rt.(*http.Transport)
+	if !ok {
+		return errors.New("protocols can only be set with an HTTP client that uses *http.Transport")
+	}
+
+	pt := &protocolTransport{
+		base: base,
+		api:  withProtocol(transport, c.apiProto),
+		cdn:  withProtocol(transport, c.cdnProto),
+	}
+	if c.baseQueryURL.Host != c.baseAPIURL.Host {
+		pt.cdnHost = c.baseQueryURL.Host
+	}
+
+	hc := *c.hc
+	hc.Transport = pt
+	c.hc = &hc
+	return nil
+}
+
+// withProtocol returns a copy of the transport restricted to the given protocol, or the
+// transport itself if no protocol is given.
+func withProtocol(t *http.Transport, proto string) *http.Transport {
+	switch proto {
+	case ProtocolHTTP1:
+		t = t.Clone()
+		t.ForceAttemptHTTP2 = false
+		// A non-nil, empty map disables HTTP/2.
+		t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		if t.TLSClientConfig == nil {
+			t.TLSClientConfig = &tls.Config{}
+		}
+		t.TLSClientConfig.NextProtos = []string{"http/1.1"}
+	case ProtocolHTTP2:
+		t = t.Clone()
+		t.ForceAttemptHTTP2 = true
+	}
+	return t
+}
@@ -1,9 +1,18 @@
 package sanity
 
 import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"strings"
+	"time"
 )
 
 func isStatusCodeRetriable(code int) bool {
@@ -15,6 +24,18 @@ func isStatusCodeRetriable(code int) bool {
 	}
 }
 
+// isIdempotentStatusCodeRetriable reports whether a request that is safe to repeat, such
+// as a mutation with a transaction ID, should be retried. Unlike isStatusCodeRetriable,
+// this includes bad gateway responses, where the request may or may not have been applied.
+func isIdempotentStatusCodeRetriable(code int) bool {
+	return code == http.StatusBadGateway || isStatusCodeRetriable(code)
+}
+
+func isErrorRetriable(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
 func isMethodRetriable(method string) bool {
 	switch method {
 	case http.MethodGet, http.MethodHead, http.MethodDelete, http.MethodOptions:
@@ -24,6 +45,194 @@ func isMethodRetriable(method string) bool {
 	}
 }
 
+// sleep waits for the duration to pass, or returns the context's error if it is done first.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func generateID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generating random ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// withArrayKey adds a random _key to a JSON object if it does not have one. Other values are
+// returned unchanged. The key is inserted as the first field, so that the order of the other
+// fields is preserved.
+func withArrayKey(b []byte) ([]byte, error) {
+	trimmed := bytes.TrimSpace(b)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return b, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(trimmed, &fields); err != nil {
+		return nil, fmt.Errorf("decoding array item: %w", err)
+	}
+	if _, ok := fields["_key"]; ok {
+		return b, nil
+	}
+
+	id, err := generateID()
+	if err != nil {
+		return nil, err
+	}
+	return prependField(trimmed, len(fields) > 0, "_key", id[:12])
+}
+
+// withDocumentID adds an _id from gen to a JSON object if it does not have one, or if it is
+// empty. Other values are returned unchanged.
+func withDocumentID(b []byte, gen func() string) ([]byte, error) {
+	trimmed := bytes.TrimSpace(b)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return b, nil
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(trimmed, &fields); err != nil {
+		return nil, fmt.Errorf("decoding document: %w", err)
+	}
+	raw, ok := fields["_id"]
+	if ok && string(raw) != `""` {
+		return b, nil
+	}
+
+	id := gen()
+	if id == "" {
+		return nil, errors.New("ID generator returned an empty ID")
+	}
+
+	if ok {
+		// The empty _id is replaced, which loses the order of the fields.
+		delete(fields, "_id")
+		var err error
+		if trimmed, err = json.Marshal(fields); err != nil {
+			return nil, fmt.Errorf("encoding document: %w", err)
+		}
+	}
+	return prependField(trimmed, len(fields) > 0, "_id", id)
+}
+
+const draftsPrefix = "drafts."
+
+// draftID returns the ID of the draft of the document with the given ID, which is the ID
+// itself if it is already a draft ID.
+func draftID(id string) string {
+	if strings.HasPrefix(id, draftsPrefix) {
+		return id
+	}
+	return draftsPrefix + id
+}
+
+// withDraftID replaces the _id of a JSON object with its draft ID, preserving the order of
+// the fields. An object without an _id gets a random draft ID, since the API would otherwise
+// generate a published one. Other values are returned unchanged.
+func withDraftID(b []byte) ([]byte, error) {
+	trimmed := bytes.TrimSpace(b)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return b, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(trimmed))
+	if _, err := dec.Token(); err != nil {
+		return nil, fmt.Errorf("decoding document: %w", err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	hasID := false
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("decoding document: %w", err)
+		}
+		key, _ := tok.(string)
+
+		var val json.RawMessage
+		if err := dec.Decode(&val); err != nil {
+			return nil, fmt.Errorf("decoding document: %w", err)
+		}
+		if key == "_id" {
+			var id string
+			if err := json.Unmarshal(val, &id); err != nil {
+				return nil, fmt.Errorf("decoding document _id: %w", err)
+			}
+			if val, err = json.Marshal(draftID(id)); err != nil {
+				return nil, err
+			}
+			hasID = true
+		}
+
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(val)
+	}
+	buf.WriteByte('}')
+
+	if !hasID {
+		id, err := generateID()
+		if err != nil {
+			return nil, err
+		}
+		return prependField(buf.Bytes(), buf.Len() > 2, "_id", draftsPrefix+id)
+	}
+	return buf.Bytes(), nil
+}
+
+// prependField inserts a string field as the first field of a JSON object, so that the order
+// of the other fields is preserved.
+func prependField(obj []byte, hasFields bool, name, val string) ([]byte, error) {
+	b, err := json.Marshal(val)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(`{"` + name + `":`)
+	buf.Write(b)
+	if hasFields {
+		buf.WriteByte(',')
+	}
+	buf.Write(obj[1:])
+	return buf.Bytes(), nil
+}
+
+// unmarshalJSON is like json.Unmarshal, except that numbers decoded into untyped values,
+// such as interface{} or api.Document, are json.Number rather than float64 if useNumber
+// is set.
+func unmarshalJSON(data []byte, dest interface{}, useNumber bool) error {
+	if !useNumber {
+		return json.Unmarshal(data, dest)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(dest); err != nil {
+		return err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return errors.New("invalid character after top-level value")
+	}
+	return nil
+}
+
 func marshalJSON(val interface{}) (*json.RawMessage, error) {
 	switch val := val.(type) {
 	case *json.RawMessage:
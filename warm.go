@@ -0,0 +1,42 @@
+package sanity
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// warmConcurrency is the maximum number of queries WarmCache runs at the same time.
+const warmConcurrency = 4
+
+// WarmCache runs each of the queries, with the same parameters, so that their results are
+// cached by the API CDN and later requests for them are answered quickly, such as after a
+// deploy or a mutation that invalidated the cache. The results are discarded. This only
+// makes sense for a client that uses the CDN, as enabled with WithCDN(true); otherwise the
+// queries are simply run against the API. Up to four queries are run at the same time. If
+// any of them fail, the errors of all failed queries are returned joined.
+func (c *Client) WarmCache(ctx context.Context, queries []string, params ...QueryParam) error {
+	errs := make([]error, len(queries))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, warmConcurrency)
+	for i, query := range queries {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, query string) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			if _, err := c.Query(query).withParams(params).DoRaw(ctx, io.Discard); err != nil {
+				errs[i] = fmt.Errorf("warming query %q: %w", query, err)
+			}
+		}(i, query)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
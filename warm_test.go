@@ -0,0 +1,101 @@
+package sanity_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	sanity "github.com/sanity-io/client-go"
+)
+
+// redirectTransport sends every request to the server, recording the host it was meant for.
+type redirectTransport struct {
+	server *httptest.Server
+
+	mu    sync.Mutex
+	hosts []string
+}
+
+func (t *redirectTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.mu.Lock()
+	t.hosts = append(t.hosts, req.URL.Host)
+	t.mu.Unlock()
+
+	u, err := url.Parse(t.server.URL)
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.URL.Scheme = u.Scheme
+	req.URL.Host = u.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestWarmCache(t *testing.T) {
+	var mu sync.Mutex
+	var queries []string
+
+	mux := chi.NewRouter()
+	mux.Get("/v1/data/query/myDataset", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, `"post"`, r.URL.Query().Get("$type"))
+
+		mu.Lock()
+		queries = append(queries, r.URL.Query().Get("query"))
+		mu.Unlock()
+
+		if r.URL.Query().Get("query") == "invalid" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":{"description":"parse error","type":"queryParseError"}}`))
+			return
+		}
+		_, err := w.Write([]byte(`{"result":[]}`))
+		assert.NoError(t, err)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	transport := &redirectTransport{server: server}
+	c, err := sanity.VersionV1.NewClient("myProject", "myDataset",
+		sanity.WithCDN(true),
+		sanity.WithHTTPClient(&http.Client{Transport: transport}))
+	require.NoError(t, err)
+
+	t.Run("runs queries against the CDN", func(t *testing.T) {
+		hot := []string{
+			`*[_type == $type] | order(_createdAt desc)[0...10]`,
+			`count(*[_type == $type])`,
+			`*[_type == $type && featured]`,
+			`*[_type == $type && defined(slug)]`,
+			`*[_type == $type]{title}`,
+		}
+		require.NoError(t, c.WarmCache(context.Background(), hot, sanity.Param("type", "post")))
+
+		sort.Strings(queries)
+		sort.Strings(hot)
+		assert.Equal(t, hot, queries)
+
+		require.Len(t, transport.hosts, len(hot))
+		for _, host := range transport.hosts {
+			assert.Equal(t, "myProject.apicdn.sanity.io", host)
+		}
+	})
+
+	t.Run("aggregates errors", func(t *testing.T) {
+		err := c.WarmCache(context.Background(), []string{"*", "invalid"}, sanity.Param("type", "post"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `warming query "invalid"`)
+		assert.NotContains(t, err.Error(), `warming query "*"`)
+
+		var reqErr *sanity.RequestError
+		assert.True(t, errors.As(err, &reqErr))
+	})
+}
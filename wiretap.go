@@ -0,0 +1,85 @@
+package sanity
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// requestBody returns a copy of the body of the request. A body that cannot be read again
+// is buffered, so that it can still be sent.
+func requestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer func() {
+			_ = body.Close()
+		}()
+		return io.ReadAll(body)
+	}
+
+	b, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(b))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(b)), nil
+	}
+	return b, nil
+}
+
+// tapResponse wraps the body of the response so that the wire tap is called with the request
+// body and everything read from the response body once it is closed.
+func (c *Client) tapResponse(reqBody []byte, resp *http.Response) {
+	resp.Body = &tapReadCloser{
+		ReadCloser: resp.Body,
+		done: func(respBody []byte) {
+			c.wireTap(reqBody, respBody)
+		},
+	}
+}
+
+// tapReadCloser records what is read from a response body, without otherwise changing how
+// it is read.
+type tapReadCloser struct {
+	io.ReadCloser
+	buf  bytes.Buffer
+	once sync.Once
+	done func([]byte)
+}
+
+func (r *tapReadCloser) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.buf.Write(p[:n])
+	return n, err
+}
+
+func (r *tapReadCloser) Close() error {
+	err := r.ReadCloser.Close()
+	r.once.Do(func() {
+		r.done(r.buf.Bytes())
+	})
+	return err
+}
+
+// tapRequestBody returns the body of the request for the wire tap, if one is set.
+func (c *Client) tapRequestBody(req *http.Request) ([]byte, error) {
+	if c.wireTap == nil {
+		return nil, nil
+	}
+	b, err := requestBody(req)
+	if err != nil {
+		return nil, fmt.Errorf("[%s %s] reading body for wire tap: %w", req.Method, req.URL.String(), err)
+	}
+	return b, nil
+}